@@ -1,32 +1,85 @@
 package app
 
 import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
 	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Width     int
-	Height    int
-	Title     string
-	FrameRate uint32
+	Width          int
+	Height         int
+	Title          string
+	FrameRate      uint32 // Updates per second
+	VSync          bool   // Wait for the display refresh when presenting
+	Headless       bool   // Render into an offscreen surface instead of a window
+	NoRender       bool   // Only run update callbacks, skip all rendering
+	ScreenshotAt   int    // Frame to save as a PNG (0: disabled)
+	ScreenshotPath string // Where to save the screenshot
+
+	MetricsLog      io.Writer     // Receives the frame report as JSON lines (nil: off)
+	MetricsInterval time.Duration // Time between frame reports
+
+	LogHistory *LogHistory // Recent log lines for crash reports (optional)
 }
 
 // App is the main application
 type App struct {
 	w               *sdl.Window
 	r               *sdl.Renderer
+	s               *sdl.Surface // Offscreen render target in headless mode
 	c               *Config
+	frames          int // Number of rendered frames
 	quit            chan bool
+	quitOnce        *sync.Once // Closes quit once per run
+	resources       []resource // Registered resources, oldest first
 	keyCallbacks    []keyCallback
+	mouseCallbacks  []func(x, y int32, button uint8)
+	motionCallbacks []func(x, y int32)
+	touchCallbacks  []func(finger int64, x, y int32, typ uint32)
+	updateCallbacks []func()
 	renderCallbacks renderCallbacks
+	quitCallbacks   []func()
+
+	// Text input receives all key presses while set
+	textKeyCallback func(key sdl.Keycode)
+	textCallback    func(text string)
+
+	frameCap   int       // Rendered frames per second (0: one per update)
+	lastUpdate time.Time // Time the last update was due with a frame cap
+
+	buttonKeys map[uint8]sdl.Keycode // Keys game controller buttons press
+	background sdl.Color             // Color the window is cleared with
+
+	frameTime time.Duration // Duration of the last update & render step
+	fps       float64       // Smoothed frames per second
+	lastFrame time.Time
+
+	metrics metrics   // Frame times of the recent frames
+	cur     frameTime // Frame times of the current frame
+	lastLog time.Time // Last metrics log entry
+
+	err            error                 // Error that stopped the app
+	crashDir       string                // Where crash reports are written
+	stateCallbacks map[string]func() any // State for crash reports by name
 }
 
 // New returns a new app instance
 func New(c *Config) (*App, error) {
-	a := &App{c: c}
+	a := &App{c: c, buttonKeys: map[uint8]sdl.Keycode{}}
+	a.resetQuit()
 	if err := a.setup(); err != nil {
 		return nil, err
 	}
@@ -47,17 +100,25 @@ func (a *App) setup() error {
 	return nil
 }
 
-// Run starts the main app loop
-// a bool true on the quit channel will break the loop and quit the app
-func (a *App) Run() int {
-	a.quit = make(chan bool)
+// Run starts the main app loop and returns the exit code
+// Quit breaks the loop and quits the app. Runs stopped by Fail or a panic
+// write a crash report and return 1 or 2.
+func (a *App) Run() (code int) {
+	// Apps can run again after they quit
+	defer a.resetQuit()
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("panic", "err", r)
+			a.crash(fmt.Sprint(r), debug.Stack())
+			code = 2
+		}
+	}()
 
 	sort.Sort(a.renderCallbacks)
 
 loop:
 	for {
-		a.clearWindow()
-
 		a.handleEvents()
 
 		select {
@@ -66,47 +127,201 @@ loop:
 		default:
 		}
 
+		a.step(a.updatesDue())
+
+		if a.frames == a.c.ScreenshotAt {
+			// Nothing left to do for a headless run once the frame is saved
+			if err := a.SaveScreenshot(a.c.ScreenshotPath); err != nil {
+				a.Fail(fmt.Errorf("couldn't save screenshot: %v", err))
+			} else if a.c.Headless {
+				a.Quit()
+			}
+		}
+
+		a.present()
+		a.measureFrame()
+
+		if !a.c.Headless {
+			sdl.Delay(1000 / a.renderRate())
+		}
+	}
+
+	for _, qc := range a.quitCallbacks {
+		qc()
+	}
+
+	if a.err != nil {
+		a.crash(a.err.Error(), nil)
+		return 1
+	}
+
+	return 0
+}
+
+// RunFrames renders n frames without handling input or waiting between frames
+// The last frame is left unpresented so it can be captured
+func (a *App) RunFrames(n int) {
+	sort.Sort(a.renderCallbacks)
+
+	for i := 0; i < n; i++ {
+		a.step(1)
+
+		if i < n-1 {
+			a.present()
+		}
+		a.measureFrame()
+	}
+}
+
+// step runs all update callbacks a number of times, then clears the window
+// and runs all render callbacks unless rendering is disabled
+func (a *App) step(updates int) {
+	start := time.Now()
+	a.cur = frameTime{}
+
+	for i := 0; i < updates; i++ {
+		for _, uc := range a.updateCallbacks {
+			uc()
+		}
+	}
+	a.cur.update = time.Since(start)
+
+	if !a.c.NoRender {
+		a.clearWindow()
+
 		for _, rc := range a.renderCallbacks {
 			rc.callback()
 		}
+	}
+	a.cur.render = time.Since(start) - a.cur.update
+	a.frameTime = time.Since(start)
 
-		a.r.Present()
-		sdl.Delay(1000 / a.c.FrameRate)
+	a.frames++
+}
+
+// present shows the rendered frame
+func (a *App) present() {
+	if a.c.NoRender {
+		return
 	}
 
-	return 0
+	start := time.Now()
+	a.r.Present()
+	a.cur.present = time.Since(start)
+}
+
+// measureFrame records the frame times and updates the frame rate with the
+// time since the last frame
+func (a *App) measureFrame() {
+	a.metrics.add(a.cur)
+	a.logMetrics()
+
+	now := time.Now()
+	if !a.lastFrame.IsZero() {
+		if d := now.Sub(a.lastFrame).Seconds(); d > 0 {
+			if a.fps == 0 {
+				a.fps = 1 / d
+			}
+			a.fps = 0.9*a.fps + 0.1/d
+		}
+	}
+	a.lastFrame = now
+}
+
+// GetFrameStats returns the measured frames per second and the time the last
+// frame took to update & render
+func (a *App) GetFrameStats() (fps float64, frameTime time.Duration) {
+	return a.fps, a.frameTime
+}
+
+// Quit stops the main app loop, it can be called any number of times
+func (a *App) Quit() {
+	a.quitOnce.Do(func() {
+		close(a.quit)
+	})
+}
+
+// resetQuit prepares the quit channel for the next run
+func (a *App) resetQuit() {
+	a.quit = make(chan bool)
+	a.quitOnce = &sync.Once{}
 }
 
 // setupWindow sets up the app window
+// In headless mode an offscreen surface is created instead
 func (a *App) setupWindow() error {
 	var err error
 
-	sdl.Do(func() {
-		a.w, err = sdl.CreateWindow(
-			a.c.Title,
-			sdl.WINDOWPOS_UNDEFINED,
-			sdl.WINDOWPOS_UNDEFINED,
-			a.c.Width,
-			a.c.Height,
-			sdl.WINDOW_OPENGL,
-		)
-	})
+	if a.c.Headless {
+		sdl.Do(func() {
+			a.s, err = sdl.CreateRGBSurface(
+				0,
+				int32(a.c.Width),
+				int32(a.c.Height),
+				32,
+				0x000000FF,
+				0x0000FF00,
+				0x00FF0000,
+				0xFF000000,
+			)
+		})
+
+		return err
+	}
+
+	// Fall back to a plain window if there is no OpenGL support
+	for _, flags := range []uint32{sdl.WINDOW_OPENGL, sdl.WINDOW_SHOWN} {
+		sdl.Do(func() {
+			a.w, err = sdl.CreateWindow(
+				a.c.Title,
+				sdl.WINDOWPOS_UNDEFINED,
+				sdl.WINDOWPOS_UNDEFINED,
+				a.c.Width,
+				a.c.Height,
+				flags,
+			)
+		})
+
+		if err == nil {
+			return nil
+		}
+	}
 
 	return err
 }
 
 // setupRenderer sets up the renderer
+// Accelerated rendering is preferred, software rendering is the fallback
 func (a *App) setupRenderer() error {
 	var err error
-	sdl.Do(func() {
-		a.r, err = sdl.CreateRenderer(a.w, -1, sdl.RENDERER_ACCELERATED)
-	})
+
+	if a.c.Headless {
+		sdl.Do(func() {
+			a.r, err = sdl.CreateSoftwareRenderer(a.s)
+		})
+	} else {
+		vsync := uint32(0)
+		if a.c.VSync {
+			vsync = sdl.RENDERER_PRESENTVSYNC
+		}
+		for _, flags := range []uint32{sdl.RENDERER_ACCELERATED | vsync, sdl.RENDERER_SOFTWARE} {
+			sdl.Do(func() {
+				a.r, err = sdl.CreateRenderer(a.w, -1, flags)
+			})
+
+			if err == nil {
+				break
+			}
+		}
+	}
 
 	if err != nil {
-		return err
+		return fmt.Errorf("couldn't create renderer: %v", err)
 	}
 
+	// The game keeps its size when the window changes, it is scaled to fit
 	sdl.Do(func() {
+		a.r.SetLogicalSize(int32(a.c.Width), int32(a.c.Height))
 		a.clearWindow()
 	})
 
@@ -116,7 +331,7 @@ func (a *App) setupRenderer() error {
 // clearWindow clears the window
 func (a *App) clearWindow() {
 	a.r.Clear()
-	a.r.SetDrawColor(0, 0, 0, 0xFF)
+	a.r.SetDrawColor(a.background.R, a.background.G, a.background.B, 0xFF)
 	a.r.FillRect(
 		&sdl.Rect{X: 0, Y: 0, W: int32(a.c.Width), H: int32(a.c.Height)},
 	)
@@ -128,30 +343,72 @@ func (a *App) handleEvents() {
 		for e := sdl.PollEvent(); e != nil; e = sdl.PollEvent() {
 			switch e.(type) {
 			case *sdl.QuitEvent:
-				go func() {
-					a.quit <- true
-					close(a.quit)
-				}()
+				a.Quit()
+			case *sdl.TextInputEvent:
+				if a.textCallback != nil {
+					text := e.(*sdl.TextInputEvent).Text
+					a.textCallback(strings.TrimRight(string(text[:]), "\x00"))
+				}
 			case *sdl.KeyDownEvent:
-				switch e.(*sdl.KeyDownEvent).Keysym.Sym {
-				case sdl.K_q:
-					go func() {
-						a.quit <- true
-						close(a.quit)
-					}()
-				default:
-					// Externally registered handlers
-					for _, kh := range a.keyCallbacks {
-						if kh.key == e.(*sdl.KeyDownEvent).Keysym.Sym {
-							kh.callback()
-						}
-					}
+				a.pressKey(e.(*sdl.KeyDownEvent).Keysym.Sym)
+			case *sdl.ControllerDeviceEvent:
+				if ce := e.(*sdl.ControllerDeviceEvent); ce.Type == sdl.CONTROLLERDEVICEADDED {
+					a.openController(int(ce.Which))
+				}
+			case *sdl.ControllerButtonEvent:
+				ce := e.(*sdl.ControllerButtonEvent)
+				if key, ok := a.buttonKeys[ce.Button]; ok && ce.Type == sdl.CONTROLLERBUTTONDOWN {
+					a.pressKey(key)
+				}
+			case *sdl.MouseButtonEvent:
+				me := e.(*sdl.MouseButtonEvent)
+				if me.Type != sdl.MOUSEBUTTONDOWN || a.fromTouch(me.Which) {
+					continue
+				}
+				for _, mc := range a.mouseCallbacks {
+					mc(me.X, me.Y, me.Button)
+				}
+			case *sdl.MouseMotionEvent:
+				me := e.(*sdl.MouseMotionEvent)
+				if a.fromTouch(me.Which) {
+					continue
+				}
+				for _, mc := range a.motionCallbacks {
+					mc(me.X, me.Y)
+				}
+			case *sdl.TouchFingerEvent:
+				// Finger positions are relative to the window
+				te := e.(*sdl.TouchFingerEvent)
+				x, y := int32(te.X*float32(a.c.Width)), int32(te.Y*float32(a.c.Height))
+				for _, tc := range a.touchCallbacks {
+					tc(te.FingerID, x, y, te.Type)
 				}
 			}
 		}
 	})
 }
 
+// pressKey runs the callbacks of a key
+func (a *App) pressKey(key sdl.Keycode) {
+	// Text input takes all keys, even the quit key
+	if a.textKeyCallback != nil {
+		a.textKeyCallback(key)
+		return
+	}
+
+	switch key {
+	case sdl.K_q:
+		a.Quit()
+	default:
+		// Externally registered handlers
+		for _, kh := range a.keyCallbacks {
+			if kh.key == key {
+				kh.callback()
+			}
+		}
+	}
+}
+
 // keyCallback defines key and callback associations
 type keyCallback struct {
 	key      sdl.Keycode
@@ -171,6 +428,68 @@ func (a *App) ClearKeyCallbacks() {
 	a.keyCallbacks = []keyCallback{}
 }
 
+// StartTextInput sends typed text and all key presses to the callbacks until
+// StopTextInput is called, key callbacks don't fire meanwhile
+func (a *App) StartTextInput(keyCallback func(key sdl.Keycode), textCallback func(text string)) {
+	a.textKeyCallback = keyCallback
+	a.textCallback = textCallback
+	sdl.StartTextInput()
+}
+
+// StopTextInput stops sending text input to the text callbacks
+func (a *App) StopTextInput() {
+	if a.textKeyCallback == nil {
+		return
+	}
+
+	a.textKeyCallback = nil
+	a.textCallback = nil
+	sdl.StopTextInput()
+}
+
+// RegisterMouseCallback registers a callback for mouse button presses
+func (a *App) RegisterMouseCallback(callback func(x, y int32, button uint8)) {
+	a.mouseCallbacks = append(a.mouseCallbacks, callback)
+}
+
+// RegisterMotionCallback registers a callback for mouse movements
+func (a *App) RegisterMotionCallback(callback func(x, y int32)) {
+	a.motionCallbacks = append(a.motionCallbacks, callback)
+}
+
+// RegisterTouchCallback registers a callback for fingers touching, moving on
+// & leaving the screen, typ is sdl.FINGERDOWN, sdl.FINGERMOTION or
+// sdl.FINGERUP
+// SDL turns touches into mouse events as well, they are dropped while touch
+// callbacks are registered so a touch isn't handled twice.
+func (a *App) RegisterTouchCallback(callback func(finger int64, x, y int32, typ uint32)) {
+	a.touchCallbacks = append(a.touchCallbacks, callback)
+}
+
+// fromTouch returns true if a mouse event was made from a touch that the
+// touch callbacks handle
+func (a *App) fromTouch(which uint32) bool {
+	return which == sdl.TOUCH_MOUSEID && len(a.touchCallbacks) > 0
+}
+
+// ClearMouseCallbacks removes all mouse, motion & touch callbacks
+func (a *App) ClearMouseCallbacks() {
+	a.mouseCallbacks = []func(x, y int32, button uint8){}
+	a.motionCallbacks = []func(x, y int32){}
+	a.touchCallbacks = []func(finger int64, x, y int32, typ uint32){}
+}
+
+// RegisterUpdateCallback registers a callback that will be called on each
+// cycle before rendering, even if rendering is disabled
+func (a *App) RegisterUpdateCallback(callback func()) {
+	a.updateCallbacks = append(a.updateCallbacks, callback)
+}
+
+// ClearUpdateCallbacks removes all update callbacks
+func (a *App) ClearUpdateCallbacks() {
+	a.updateCallbacks = []func(){}
+}
+
 // renderCallback defines callbacks to inject into the render loop
 type renderCallback struct {
 	priority int
@@ -198,10 +517,31 @@ func (a *App) ClearRenderCallbacks() {
 	a.renderCallbacks = renderCallbacks{}
 }
 
-// ClearCallbacks removes all key & render callbacks
+// RegisterQuitCallback registers a callback that will be called once the main
+// loop has stopped
+func (a *App) RegisterQuitCallback(callback func()) {
+	a.quitCallbacks = append(a.quitCallbacks, callback)
+}
+
+// ClearQuitCallbacks removes all quit callbacks
+func (a *App) ClearQuitCallbacks() {
+	a.quitCallbacks = []func(){}
+}
+
+// ClearCallbacks removes all key, mouse, update, render & quit callbacks and
+// stops text input
 func (a *App) ClearCallbacks() {
+	a.StopTextInput()
 	a.ClearKeyCallbacks()
+	a.ClearMouseCallbacks()
+	a.ClearUpdateCallbacks()
 	a.ClearRenderCallbacks()
+	a.ClearQuitCallbacks()
+}
+
+// GetFrameRate returns the number of frames per second
+func (a *App) GetFrameRate() uint32 {
+	return a.c.FrameRate
 }
 
 // GetRenderer returns a renderer instance
@@ -209,13 +549,57 @@ func (a *App) GetRenderer() *sdl.Renderer {
 	return a.r
 }
 
-// Destroy destroys the app
+// Capture reads the current frame into an image
+func (a *App) Capture() (*image.RGBA, error) {
+	w, h, err := a.r.GetRendererOutputSize()
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	err = a.r.ReadPixels(
+		nil,
+		sdl.PIXELFORMAT_ABGR8888,
+		unsafe.Pointer(&img.Pix[0]),
+		img.Stride,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read pixels: %v", err)
+	}
+
+	return img, nil
+}
+
+// SaveScreenshot saves the current frame as a PNG
+func (a *App) SaveScreenshot(path string) error {
+	img, err := a.Capture()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
+
+// Destroy closes all registered resources and destroys the app
 func (a *App) Destroy() {
+	a.CloseAll()
+
 	sdl.Do(func() {
-		a.w.Destroy()
+		a.r.Destroy()
 	})
 
 	sdl.Do(func() {
-		a.r.Destroy()
+		if a.w != nil {
+			a.w.Destroy()
+		}
+		if a.s != nil {
+			a.s.Free()
+		}
 	})
 }
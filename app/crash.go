@@ -0,0 +1,104 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// crashReport holds what is known about a failed run
+type crashReport struct {
+	Time  time.Time      `json:"time"`
+	Error string         `json:"error"`
+	Stack string         `json:"stack,omitempty"`
+	Log   []string       `json:"log,omitempty"`
+	State map[string]any `json:"state,omitempty"`
+}
+
+// RegisterStateCallback registers a callback that adds state to crash reports
+// under name, state callbacks aren't removed by ClearCallbacks
+func (a *App) RegisterStateCallback(name string, callback func() any) {
+	if a.stateCallbacks == nil {
+		a.stateCallbacks = map[string]func() any{}
+	}
+	a.stateCallbacks[name] = callback
+}
+
+// SetCrashDir sets the directory crash reports are written to (default: the
+// temp dir)
+func (a *App) SetCrashDir(dir string) {
+	a.crashDir = dir
+}
+
+// Fail logs an error and stops the main loop, Run writes a crash report and
+// returns a non-zero exit code
+func (a *App) Fail(err error) {
+	if a.err != nil {
+		return
+	}
+
+	slog.Error("fatal error", "err", err)
+	a.err = err
+	a.Quit()
+}
+
+// Err returns the error passed to Fail or nil
+func (a *App) Err() error {
+	return a.err
+}
+
+// writeCrashReport writes a crash report and returns its path
+func (a *App) writeCrashReport(reason string, stack []byte) (string, error) {
+	report := &crashReport{
+		Time:  time.Now(),
+		Error: reason,
+		Stack: string(stack),
+		State: map[string]any{},
+	}
+	if a.c.LogHistory != nil {
+		report.Log = a.c.LogHistory.Lines()
+	}
+
+	// State callbacks may fail on the state that caused the crash
+	for name, sc := range a.stateCallbacks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					report.State[name] = fmt.Sprintf("unavailable: %v", r)
+				}
+			}()
+			report.State[name] = sc()
+		}()
+	}
+
+	dir := a.crashDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s.json", report.Time.Format("20060102-150405")))
+
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// crash writes a crash report and logs where it is
+func (a *App) crash(reason string, stack []byte) {
+	path, err := a.writeCrashReport(reason, stack)
+	if err != nil {
+		slog.Error("couldn't write crash report", "err", err)
+		return
+	}
+
+	slog.Error("crash report written", "path", path)
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"strings"
+	"sync"
+)
+
+// LogHistory keeps the most recent log lines for crash reports, it is used as
+// an additional writer of the log handler
+type LogHistory struct {
+	mu    sync.Mutex
+	size  int
+	lines []string
+}
+
+// NewLogHistory returns a log history of size lines
+func NewLogHistory(size int) *LogHistory {
+	return &LogHistory{size: size}
+}
+
+// Write implements io.Writer, p holds one or more log lines
+func (h *LogHistory) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		h.lines = append(h.lines, line)
+	}
+	if len(h.lines) > h.size {
+		h.lines = append([]string{}, h.lines[len(h.lines)-h.size:]...)
+	}
+
+	return len(p), nil
+}
+
+// Lines returns the recent log lines
+func (h *LogHistory) Lines() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]string{}, h.lines...)
+}
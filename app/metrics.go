@@ -0,0 +1,184 @@
+package app
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metricsFrames is the number of recent frames the metrics cover
+const metricsFrames = 900
+
+// frameTime holds how long the phases of a frame took
+type frameTime struct {
+	update  time.Duration
+	render  time.Duration
+	present time.Duration
+}
+
+// metrics records the frame times of the recent frames
+type metrics struct {
+	mu     sync.Mutex
+	frames []frameTime // Ring buffer
+	next   int         // Index of the next frame in frames
+	count  int         // Number of frames recorded in total
+}
+
+// add records a frame
+func (m *metrics) add(f frameTime) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.frames) < metricsFrames {
+		m.frames = append(m.frames, f)
+	} else {
+		m.frames[m.next] = f
+	}
+	m.next = (m.next + 1) % metricsFrames
+	m.count++
+}
+
+// Percentiles holds frame time percentiles in milliseconds
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// FrameReport summarizes the frame times of the recent frames
+type FrameReport struct {
+	Frames  int         `json:"frames"` // Frames the report covers
+	Total   int         `json:"total"`  // Frames since the start
+	Update  Percentiles `json:"update"`
+	Render  Percentiles `json:"render"`
+	Present Percentiles `json:"present"`
+	Frame   Percentiles `json:"frame"` // Update, render & present
+}
+
+// report summarizes the recorded frames
+func (m *metrics) report() FrameReport {
+	m.mu.Lock()
+	frames := append([]frameTime{}, m.frames...)
+	count := m.count
+	m.mu.Unlock()
+
+	phase := func(d func(f frameTime) time.Duration) Percentiles {
+		ms := make([]float64, len(frames))
+		for i, f := range frames {
+			ms[i] = float64(d(f).Microseconds()) / 1000
+		}
+		sort.Float64s(ms)
+
+		return Percentiles{
+			P50: percentile(ms, 0.50),
+			P95: percentile(ms, 0.95),
+			P99: percentile(ms, 0.99),
+			Max: percentile(ms, 1),
+		}
+	}
+
+	return FrameReport{
+		Frames:  len(frames),
+		Total:   count,
+		Update:  phase(func(f frameTime) time.Duration { return f.update }),
+		Render:  phase(func(f frameTime) time.Duration { return f.render }),
+		Present: phase(func(f frameTime) time.Duration { return f.present }),
+		Frame:   phase(func(f frameTime) time.Duration { return f.update + f.render + f.present }),
+	}
+}
+
+// percentile returns the nearest rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	i := int(p*float64(len(sorted))+0.5) - 1
+
+	return sorted[max(0, min(i, len(sorted)-1))]
+}
+
+// Write writes the report as a table
+func (r FrameReport) Write(w io.Writer) {
+	fmt.Fprintf(w, "%d frames\n", r.Frames)
+	fmt.Fprintf(w, "%-8s %8s %8s %8s %8s\n", "phase", "p50 ms", "p95 ms", "p99 ms", "max ms")
+	for _, p := range []struct {
+		name string
+		p    Percentiles
+	}{
+		{"update", r.Update},
+		{"render", r.Render},
+		{"present", r.Present},
+		{"frame", r.Frame},
+	} {
+		fmt.Fprintf(w, "%-8s %8.2f %8.2f %8.2f %8.2f\n", p.name, p.p.P50, p.p.P95, p.p.P99, p.p.Max)
+	}
+}
+
+// FrameReport returns the frame times of the recent frames
+func (a *App) FrameReport() FrameReport {
+	return a.metrics.report()
+}
+
+// logMetrics writes the frame report as a JSON line to the metrics log if
+// the log interval has passed
+func (a *App) logMetrics() {
+	if a.c.MetricsLog == nil || time.Since(a.lastLog) < a.c.MetricsInterval {
+		return
+	}
+	a.lastLog = time.Now()
+
+	data, err := json.Marshal(struct {
+		Time time.Time `json:"time"`
+		FrameReport
+	}{time.Now(), a.FrameReport()})
+	if err != nil {
+		return
+	}
+	a.c.MetricsLog.Write(append(data, '\n'))
+}
+
+// ServeDebug serves the frame report as expvar and the pprof profiles on a
+// loopback address
+func (a *App) ServeDebug(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid debug address %s: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("debug address %s isn't on localhost", addr)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen on %s: %v", addr, err)
+	}
+
+	// expvar names can only be published once per process
+	publishOnce.Do(func() {
+		expvar.Publish("frames", expvar.Func(func() any { return a.FrameReport() }))
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go http.Serve(l, mux)
+
+	return nil
+}
+
+// publishOnce guards the expvar registration
+var publishOnce sync.Once
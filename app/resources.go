@@ -0,0 +1,50 @@
+package app
+
+// Scope is how long a registered resource lives
+type Scope int
+
+const (
+	SceneScope Scope = iota // Closed on CloseScene
+	AppScope                // Closed on Destroy
+)
+
+// resource is a registered resource
+type resource struct {
+	scope Scope
+	close func()
+}
+
+// Own registers the close function of a resource, it is called when the scope
+// ends, the latest resource first
+func (a *App) Own(scope Scope, close func()) {
+	a.resources = append(a.resources, resource{scope: scope, close: close})
+}
+
+// CloseScene closes the resources of the current scene
+func (a *App) CloseScene() {
+	a.closeResources(SceneScope)
+}
+
+// CloseAll closes the resources of all scopes, Destroy calls it
+func (a *App) CloseAll() {
+	a.closeResources(AppScope)
+}
+
+// OpenResources returns the number of registered resources that haven't been
+// closed yet
+func (a *App) OpenResources() int {
+	return len(a.resources)
+}
+
+// closeResources closes the resources of a scope and all shorter scopes
+func (a *App) closeResources(scope Scope) {
+	kept := []resource{}
+	for i := len(a.resources) - 1; i >= 0; i-- {
+		if a.resources[i].scope <= scope {
+			a.resources[i].close()
+		} else {
+			kept = append([]resource{a.resources[i]}, kept...)
+		}
+	}
+	a.resources = kept
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/veandco/go-sdl2/sdl"
+	mix "github.com/veandco/go-sdl2/sdl_mixer"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// Init initializes SDL, fonts & audio and returns a function that shuts them
+// down again, headless runs use the dummy video & audio drivers
+func Init(headless bool) (func(), error) {
+	// SDL picks the drivers up from the environment on init
+	if headless {
+		os.Setenv("SDL_VIDEODRIVER", "dummy")
+		os.Setenv("SDL_AUDIODRIVER", "dummy")
+	}
+
+	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+		return nil, fmt.Errorf("couldn't initialize sdl: %v", err)
+	}
+
+	if err := ttf.Init(); err != nil {
+		sdl.Quit()
+		return nil, fmt.Errorf("couldn't initialize ttf: %v", err)
+	}
+
+	if err := mix.Init(0); err != nil {
+		ttf.Quit()
+		sdl.Quit()
+		return nil, fmt.Errorf("couldn't initialize mixer: %v", err)
+	}
+
+	sndfmt := uint16(mix.DEFAULT_FORMAT)
+	if err := mix.OpenAudio(44100, sndfmt, 2, 1024); err != nil {
+		mix.Quit()
+		ttf.Quit()
+		sdl.Quit()
+		return nil, fmt.Errorf("couldn't initialize audio: %v", err)
+	}
+
+	return func() {
+		mix.CloseAudio()
+		mix.Quit()
+		ttf.Quit()
+		sdl.Quit()
+	}, nil
+}
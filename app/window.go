@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// maxCatchUp is the most updates a capped frame runs to catch up, slower
+// frames drop the missing time
+const maxCatchUp = 5
+
+// SetWindowMode resizes the window or makes it fill the screen, the game
+// keeps its size and is scaled to the window
+func (a *App) SetWindowMode(width, height int, fullscreen bool) error {
+	if a.w == nil {
+		return nil
+	}
+
+	var err error
+	sdl.Do(func() {
+		if fullscreen {
+			err = a.w.SetFullscreen(sdl.WINDOW_FULLSCREEN_DESKTOP)
+			return
+		}
+
+		if err = a.w.SetFullscreen(0); err == nil {
+			a.w.SetSize(width, height)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't set window mode: %v", err)
+	}
+
+	return nil
+}
+
+// SetFrameCap sets the rendered frames per second, updates keep running at
+// the frame rate (0: one frame per update)
+// Headless runs ignore the cap so they stay reproducible.
+func (a *App) SetFrameCap(fps int) {
+	a.frameCap = fps
+	a.lastUpdate = time.Time{}
+}
+
+// renderRate returns the rendered frames per second
+func (a *App) renderRate() uint32 {
+	if a.frameCap > 0 {
+		return uint32(a.frameCap)
+	}
+
+	return a.c.FrameRate
+}
+
+// updatesDue returns the number of updates owed since the last frame, every
+// frame updates once unless there is a frame cap
+func (a *App) updatesDue() int {
+	if a.frameCap <= 0 || a.c.Headless {
+		return 1
+	}
+
+	tick := time.Second / time.Duration(a.c.FrameRate)
+	now := time.Now()
+	if a.lastUpdate.IsZero() || now.Sub(a.lastUpdate) > maxCatchUp*tick {
+		a.lastUpdate = now
+		return 1
+	}
+
+	n := int(now.Sub(a.lastUpdate) / tick)
+	a.lastUpdate = a.lastUpdate.Add(time.Duration(n) * tick)
+
+	return n
+}
+
+// SetBackground sets the color the window is cleared with each frame
+func (a *App) SetBackground(c sdl.Color) {
+	a.background = c
+}
+
+// MapButton makes a game controller button press a key
+func (a *App) MapButton(button uint8, key sdl.Keycode) {
+	a.buttonKeys[button] = key
+}
+
+// openController opens a connected game controller, it is closed with the app
+func (a *App) openController(index int) {
+	if !sdl.IsGameController(index) {
+		return
+	}
+
+	gc := sdl.GameControllerOpen(index)
+	if gc == nil {
+		return
+	}
+	a.Own(AppScope, gc.Close)
+}
@@ -0,0 +1,39 @@
+-- Example mod: hunters are worth double, zigzaggers sometimes fire homing
+-- shots, every third wave is a checkerboard and grunts sway in formation.
+
+local lastHit = nil
+
+function onHit(alien)
+  lastHit = alien.type
+end
+
+function onScore(event, points)
+  if event == "alien" and lastHit == "hunter" then
+    return points * 2
+  end
+  return points
+end
+
+function onFire(alien, weapon)
+  if alien.type == "zigzagger" and math.random(4) == 1 then
+    return "homing"
+  end
+  return nil
+end
+
+function onWave(level)
+  if level % 3 ~= 0 then
+    return nil
+  end
+  return { "G.G.G.G.G.", ".Z.Z.Z.Z.Z", "H.H.H.H.H." }, 2
+end
+
+function onMove(alien)
+  if alien.type == "grunt" and not alien.diving then
+    if game.tick % 16 < 8 then
+      alien.x = alien.x + 1
+    else
+      alien.x = alien.x - 1
+    end
+  end
+end
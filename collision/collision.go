@@ -0,0 +1,196 @@
+// Package collision provides axis aligned bounding box tests and a uniform
+// grid broadphase to keep hit testing cheap for many entities
+package collision
+
+// Box is an axis aligned bounding box
+type Box struct {
+	X, Y, W, H int32
+}
+
+// Intersects checks if two boxes overlap
+func (b Box) Intersects(o Box) bool {
+	return b.X < o.X+o.W && o.X < b.X+b.W && b.Y < o.Y+o.H && o.Y < b.Y+b.H
+}
+
+// Union returns the smallest box that contains both boxes
+// An empty box is ignored
+func (b Box) Union(o Box) Box {
+	if b.W == 0 && b.H == 0 {
+		return o
+	}
+	if o.W == 0 && o.H == 0 {
+		return b
+	}
+
+	x1, y1 := min(b.X, o.X), min(b.Y, o.Y)
+	x2, y2 := max(b.X+b.W, o.X+o.W), max(b.Y+b.H, o.Y+o.H)
+
+	return Box{X: x1, Y: y1, W: x2 - x1, H: y2 - y1}
+}
+
+// cell identifies a grid cell
+type cell struct {
+	x, y int32
+}
+
+// Grid is a uniform grid broadphase
+// Boxes are referenced by the ID Insert returns, IDs stay valid until the box
+// is removed and are reused afterwards
+type Grid struct {
+	size  int32          // Cell size
+	cells map[cell][]int // IDs of the boxes overlapping each cell
+	boxes []Box          // Boxes by ID
+	live  []bool         // Whether an ID is in use
+	free  []int          // Removed IDs to reuse
+	marks []uint32       // Query stamp per ID to report each box once
+	stamp uint32         // Current query stamp
+}
+
+// NewGrid returns a grid with the given cell size
+// The cell size should be around the size of the largest entity
+func NewGrid(size int32) *Grid {
+	return &Grid{
+		size:  size,
+		cells: make(map[cell][]int),
+	}
+}
+
+// Reset removes all boxes but keeps the allocated memory
+func (g *Grid) Reset() {
+	for k, ids := range g.cells {
+		g.cells[k] = ids[:0]
+	}
+	g.boxes = g.boxes[:0]
+	g.live = g.live[:0]
+	g.free = g.free[:0]
+	g.marks = g.marks[:0]
+}
+
+// Len returns the number of boxes in the grid
+func (g *Grid) Len() int {
+	return len(g.boxes) - len(g.free)
+}
+
+// Insert adds a box and returns its ID
+func (g *Grid) Insert(b Box) int {
+	var id int
+	if n := len(g.free); n > 0 {
+		id = g.free[n-1]
+		g.free = g.free[:n-1]
+		g.boxes[id] = b
+		g.live[id] = true
+	} else {
+		id = len(g.boxes)
+		g.boxes = append(g.boxes, b)
+		g.live = append(g.live, true)
+		g.marks = append(g.marks, 0)
+	}
+
+	g.link(id, b)
+
+	return id
+}
+
+// Remove removes the box of an ID, the ID may be handed out again by Insert
+func (g *Grid) Remove(id int) {
+	if id < 0 || id >= len(g.boxes) || !g.live[id] {
+		return
+	}
+
+	g.unlink(id, g.boxes[id])
+	g.live[id] = false
+	g.free = append(g.free, id)
+}
+
+// Move replaces the box of an ID, cells are only updated if the box moved
+// into other cells
+func (g *Grid) Move(id int, b Box) {
+	if id < 0 || id >= len(g.boxes) || !g.live[id] {
+		return
+	}
+
+	old := g.boxes[id]
+	g.boxes[id] = b
+	if g.cellSpan(old) == g.cellSpan(b) {
+		return
+	}
+
+	g.unlink(id, old)
+	g.link(id, b)
+}
+
+// link adds an ID to the cells a box overlaps
+func (g *Grid) link(id int, b Box) {
+	g.span(b, func(c cell) {
+		g.cells[c] = append(g.cells[c], id)
+	})
+}
+
+// unlink removes an ID from the cells a box overlaps
+func (g *Grid) unlink(id int, b Box) {
+	g.span(b, func(c cell) {
+		ids := g.cells[c]
+		for i, cid := range ids {
+			if cid == id {
+				ids[i] = ids[len(ids)-1]
+				g.cells[c] = ids[:len(ids)-1]
+				break
+			}
+		}
+	})
+}
+
+// Query calls fn for the ID of each box that intersects b until fn returns
+// false
+func (g *Grid) Query(b Box, fn func(id int) bool) {
+	g.stamp++
+	done := false
+
+	g.span(b, func(c cell) {
+		if done {
+			return
+		}
+
+		for _, id := range g.cells[c] {
+			if g.marks[id] == g.stamp {
+				continue
+			}
+			g.marks[id] = g.stamp
+
+			if g.boxes[id].Intersects(b) && !fn(id) {
+				done = true
+				return
+			}
+		}
+	})
+}
+
+// cellSpan returns the first & last cell a box overlaps
+func (g *Grid) cellSpan(b Box) [2]cell {
+	return [2]cell{
+		{x: floorDiv(b.X, g.size), y: floorDiv(b.Y, g.size)},
+		{x: floorDiv(b.X+b.W-1, g.size), y: floorDiv(b.Y+b.H-1, g.size)},
+	}
+}
+
+// span calls fn for each cell a box overlaps
+func (g *Grid) span(b Box, fn func(c cell)) {
+	cs := g.cellSpan(b)
+
+	for y := cs[0].y; y <= cs[1].y; y++ {
+		for x := cs[0].x; x <= cs[1].x; x++ {
+			fn(cell{x: x, y: y})
+		}
+	}
+}
+
+// floorDiv divides rounding towards negative infinity so that boxes left of
+// or above the origin land in their own cells
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
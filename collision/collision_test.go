@@ -0,0 +1,308 @@
+package collision
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestFloorDiv(t *testing.T) {
+	tests := []struct {
+		a, b, want int32
+	}{
+		{0, 128, 0},
+		{127, 128, 0},
+		{128, 128, 1},
+		{-1, 128, -1},
+		{-128, 128, -1},
+		{-129, 128, -2},
+		{7, -2, -4},
+		{-7, -2, 3},
+	}
+
+	for _, tt := range tests {
+		if got := floorDiv(tt.a, tt.b); got != tt.want {
+			t.Errorf("floorDiv(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Box
+		want Box
+	}{
+		{"empty left", Box{}, Box{X: 1, Y: 2, W: 3, H: 4}, Box{X: 1, Y: 2, W: 3, H: 4}},
+		{"empty right", Box{X: 1, Y: 2, W: 3, H: 4}, Box{}, Box{X: 1, Y: 2, W: 3, H: 4}},
+		{"contained", Box{X: 0, Y: 0, W: 10, H: 10}, Box{X: 2, Y: 2, W: 2, H: 2}, Box{X: 0, Y: 0, W: 10, H: 10}},
+		{"apart", Box{X: 0, Y: 0, W: 2, H: 2}, Box{X: 8, Y: 6, W: 2, H: 2}, Box{X: 0, Y: 0, W: 10, H: 8}},
+		{"negative", Box{X: -5, Y: -5, W: 2, H: 2}, Box{X: 1, Y: 1, W: 2, H: 2}, Box{X: -5, Y: -5, W: 8, H: 8}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Union(tt.b); got != tt.want {
+			t.Errorf("%s: %v.Union(%v) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// checkerMask returns a mask of w x h pixels with the top left pixel solid and
+// the other pixels alternating
+func checkerMask(w, h int32) *Mask {
+	return NewMask(w, h, func(x, y int32) uint8 {
+		if (x+y)%2 == 0 {
+			return 0xFF
+		}
+		return 0
+	}, 0x80)
+}
+
+// cornerMask returns a mask of w x h pixels where only the top left pixel is
+// solid
+func cornerMask(w, h int32) *Mask {
+	return NewMask(w, h, func(x, y int32) uint8 {
+		if x == 0 && y == 0 {
+			return 0xFF
+		}
+		return 0
+	}, 0x80)
+}
+
+func TestOverlaps(t *testing.T) {
+	corner := cornerMask(4, 4)
+
+	tests := []struct {
+		name   string
+		a      Box
+		am     *Mask
+		b      Box
+		bm     *Mask
+		expect bool
+	}{
+		{"boxes apart", Box{X: 0, Y: 0, W: 4, H: 4}, nil, Box{X: 4, Y: 0, W: 4, H: 4}, nil, false},
+		{"no masks", Box{X: 0, Y: 0, W: 4, H: 4}, nil, Box{X: 3, Y: 3, W: 4, H: 4}, nil, true},
+		{"solid pixel", Box{X: 0, Y: 0, W: 4, H: 4}, corner, Box{X: 0, Y: 0, W: 1, H: 1}, nil, true},
+		{"empty pixels", Box{X: 0, Y: 0, W: 4, H: 4}, corner, Box{X: 1, Y: 1, W: 3, H: 3}, nil, false},
+		{"stretched mask", Box{X: 0, Y: 0, W: 8, H: 8}, corner, Box{X: 1, Y: 1, W: 1, H: 1}, nil, true},
+		{"stretched empty", Box{X: 0, Y: 0, W: 8, H: 8}, corner, Box{X: 2, Y: 2, W: 6, H: 6}, nil, false},
+		{"both masks", Box{X: 0, Y: 0, W: 4, H: 4}, corner, Box{X: 0, Y: 0, W: 4, H: 4}, corner, true},
+		{"both masks apart", Box{X: 0, Y: 0, W: 4, H: 4}, corner, Box{X: 1, Y: 0, W: 4, H: 4}, corner, false},
+		{"checker offset", Box{X: 0, Y: 0, W: 4, H: 4}, checkerMask(4, 4), Box{X: 1, Y: 0, W: 4, H: 4}, checkerMask(4, 4), false},
+		{"checker aligned", Box{X: 0, Y: 0, W: 4, H: 4}, checkerMask(4, 4), Box{X: 2, Y: 0, W: 4, H: 4}, checkerMask(4, 4), true},
+	}
+
+	for _, tt := range tests {
+		if got := Overlaps(tt.a, tt.am, tt.b, tt.bm); got != tt.expect {
+			t.Errorf("%s: Overlaps = %v, want %v", tt.name, got, tt.expect)
+		}
+		if got := Overlaps(tt.b, tt.bm, tt.a, tt.am); got != tt.expect {
+			t.Errorf("%s swapped: Overlaps = %v, want %v", tt.name, got, tt.expect)
+		}
+	}
+}
+
+// query returns the sorted IDs of the boxes in g that intersect b
+func query(g *Grid, b Box) []int {
+	ids := []int{}
+	g.Query(b, func(id int) bool {
+		ids = append(ids, id)
+		return true
+	})
+	slices.Sort(ids)
+
+	return ids
+}
+
+func TestGridQuery(t *testing.T) {
+	g := NewGrid(10)
+	boxes := []Box{
+		{X: 0, Y: 0, W: 10, H: 10},     // 0: exactly one cell
+		{X: 5, Y: 5, W: 10, H: 10},     // 1: spans four cells
+		{X: -10, Y: -10, W: 10, H: 10}, // 2: cell left of and above the origin
+		{X: -3, Y: 2, W: 6, H: 2},      // 3: crosses the origin column
+		{X: 30, Y: 30, W: 1, H: 1},     // 4: far away
+	}
+	for i, b := range boxes {
+		if id := g.Insert(b); id != i {
+			t.Fatalf("Insert returned ID %d, want %d", id, i)
+		}
+	}
+
+	tests := []struct {
+		name string
+		b    Box
+		want []int
+	}{
+		{"inside one cell", Box{X: 5, Y: 0, W: 2, H: 2}, []int{0}},
+		{"over four cells", Box{X: 8, Y: 8, W: 4, H: 4}, []int{0, 1}},
+		{"right border is exclusive", Box{X: 10, Y: 0, W: 2, H: 2}, []int{}},
+		{"left border is inclusive", Box{X: 9, Y: 5, W: 1, H: 1}, []int{0, 1}},
+		{"negative cell", Box{X: -5, Y: -5, W: 1, H: 1}, []int{2}},
+		{"negative border", Box{X: -1, Y: -1, W: 1, H: 1}, []int{2}},
+		{"across the origin", Box{X: -2, Y: 3, W: 4, H: 1}, []int{0, 3}},
+		{"everything", Box{X: -100, Y: -100, W: 200, H: 200}, []int{0, 1, 2, 3, 4}},
+		{"nothing", Box{X: 50, Y: -50, W: 5, H: 5}, []int{}},
+	}
+
+	for _, tt := range tests {
+		if got := query(g, tt.b); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: Query(%v) = %v, want %v", tt.name, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGridQueryStops(t *testing.T) {
+	g := NewGrid(10)
+	for i := 0; i < 5; i++ {
+		g.Insert(Box{X: int32(i) * 20, Y: 0, W: 5, H: 5})
+	}
+
+	calls := 0
+	g.Query(Box{X: 0, Y: 0, W: 100, H: 5}, func(id int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Query called fn %d times after it returned false, want 1", calls)
+	}
+}
+
+func TestGridMoveRemove(t *testing.T) {
+	g := NewGrid(10)
+	a := g.Insert(Box{X: 0, Y: 0, W: 5, H: 5})
+	b := g.Insert(Box{X: 20, Y: 0, W: 5, H: 5})
+
+	// Within the same cell
+	g.Move(a, Box{X: 2, Y: 2, W: 5, H: 5})
+	if got := query(g, Box{X: 6, Y: 6, W: 1, H: 1}); !slices.Equal(got, []int{a}) {
+		t.Errorf("after a move within a cell Query = %v, want [%d]", got, a)
+	}
+
+	// Into other cells
+	g.Move(a, Box{X: -15, Y: 40, W: 5, H: 5})
+	if got := query(g, Box{X: 0, Y: 0, W: 10, H: 10}); len(got) != 0 {
+		t.Errorf("old cells still report %v after a move", got)
+	}
+	if got := query(g, Box{X: -12, Y: 42, W: 1, H: 1}); !slices.Equal(got, []int{a}) {
+		t.Errorf("after a move into other cells Query = %v, want [%d]", got, a)
+	}
+
+	g.Remove(b)
+	if got := query(g, Box{X: 20, Y: 0, W: 5, H: 5}); len(got) != 0 {
+		t.Errorf("removed box is still reported as %v", got)
+	}
+	if g.Len() != 1 {
+		t.Errorf("Len = %d after a remove, want 1", g.Len())
+	}
+
+	// Removing twice and moving removed boxes are no-ops
+	g.Remove(b)
+	g.Move(b, Box{X: 0, Y: 0, W: 5, H: 5})
+	if got := query(g, Box{X: 0, Y: 0, W: 5, H: 5}); len(got) != 0 {
+		t.Errorf("moved removed box is reported as %v", got)
+	}
+
+	// Removed IDs are reused
+	if c := g.Insert(Box{X: 70, Y: 70, W: 5, H: 5}); c != b {
+		t.Errorf("Insert returned ID %d, want the removed ID %d", c, b)
+	}
+	if got := query(g, Box{X: 70, Y: 70, W: 5, H: 5}); !slices.Equal(got, []int{b}) {
+		t.Errorf("reused ID Query = %v, want [%d]", got, b)
+	}
+	if g.Len() != 2 {
+		t.Errorf("Len = %d after reusing an ID, want 2", g.Len())
+	}
+
+	g.Reset()
+	if g.Len() != 0 {
+		t.Errorf("Len = %d after Reset, want 0", g.Len())
+	}
+	if got := query(g, Box{X: -100, Y: -100, W: 200, H: 200}); len(got) != 0 {
+		t.Errorf("Reset grid reports %v", got)
+	}
+}
+
+// randomBoxes returns n boxes of size bw x bh randomly placed in a w x h field
+func randomBoxes(rng *rand.Rand, n int, w, h, bw, bh int32) []Box {
+	boxes := make([]Box, n)
+	for i := range boxes {
+		boxes[i] = Box{X: rng.Int31n(w - bw), Y: rng.Int31n(h - bh), W: bw, H: bh}
+	}
+
+	return boxes
+}
+
+// benchCounts holds the entity counts the hit testing benchmarks run with
+var benchCounts = []int{50, 200, 1000, 5000}
+
+// benchScene returns n alien sized entities and n bullets placed at random
+// The field grows with n so the density stays that of 50 aliens on a
+// 1200 x 800 screen.
+func benchScene(n int) (entities, bullets []Box) {
+	f := math.Sqrt(float64(n) / 50)
+	w, h := int32(1200*f), int32(800*f)
+
+	rng := rand.New(rand.NewSource(int64(n)))
+
+	return randomBoxes(rng, n, w, h, 80, 86), randomBoxes(rng, n, w, h, 7, 9)
+}
+
+// BenchmarkBruteForce tests every bullet against every entity while the
+// entities move
+func BenchmarkBruteForce(b *testing.B) {
+	for _, n := range benchCounts {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			entities, bullets := benchScene(n)
+
+			hits := 0
+			for i := 0; i < b.N; i++ {
+				dx := int32(i%2*2 - 1)
+				for id := range entities {
+					entities[id].X += dx
+				}
+
+				for _, bt := range bullets {
+					for _, e := range entities {
+						if e.Intersects(bt) {
+							hits++
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGrid runs the same hit tests as BenchmarkBruteForce through the
+// grid, moving every entity each round like the alien grid does
+func BenchmarkGrid(b *testing.B) {
+	for _, n := range benchCounts {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			entities, bullets := benchScene(n)
+
+			g := NewGrid(128)
+			for _, e := range entities {
+				g.Insert(e)
+			}
+
+			hits := 0
+			for i := 0; i < b.N; i++ {
+				dx := int32(i%2*2 - 1)
+				for id := range entities {
+					entities[id].X += dx
+					g.Move(id, entities[id])
+				}
+
+				for _, bt := range bullets {
+					g.Query(bt, func(id int) bool {
+						hits++
+						return true
+					})
+				}
+			}
+		})
+	}
+}
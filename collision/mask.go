@@ -0,0 +1,60 @@
+package collision
+
+// Mask is a per pixel collision mask of a sprite
+type Mask struct {
+	W, H  int32
+	solid []bool
+}
+
+// NewMask builds a mask of w x h pixels
+// A pixel is solid if its alpha value is at least threshold.
+func NewMask(w, h int32, alpha func(x, y int32) uint8, threshold uint8) *Mask {
+	m := &Mask{W: w, H: h, solid: make([]bool, w*h)}
+
+	for y := int32(0); y < h; y++ {
+		for x := int32(0); x < w; x++ {
+			m.solid[y*w+x] = alpha(x, y) >= threshold
+		}
+	}
+
+	return m
+}
+
+// at checks if the screen pixel x, y is solid for a mask stretched over box b
+// A nil mask is solid everywhere.
+func (m *Mask) at(b Box, x, y int32) bool {
+	if m == nil {
+		return true
+	}
+
+	mx := (x - b.X) * m.W / b.W
+	my := (y - b.Y) * m.H / b.H
+
+	return m.solid[my*m.W+mx]
+}
+
+// Overlaps checks if two masked boxes overlap
+// The boxes are tested first, the masks only where the boxes intersect.
+// Masks are stretched over their box, a nil mask covers the whole box.
+func Overlaps(a Box, am *Mask, b Box, bm *Mask) bool {
+	if !a.Intersects(b) {
+		return false
+	}
+
+	if am == nil && bm == nil {
+		return true
+	}
+
+	x1, y1 := max(a.X, b.X), max(a.Y, b.Y)
+	x2, y2 := min(a.X+a.W, b.X+b.W), min(a.Y+a.H, b.Y+b.H)
+
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			if am.at(a, x, y) && bm.at(b, x, y) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -3,31 +3,77 @@ package game
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 
+	"github.com/MichaelThessel/spacee/collision"
 	"github.com/veandco/go-sdl2/sdl"
-	img "github.com/veandco/go-sdl2/sdl_image"
-	mix "github.com/veandco/go-sdl2/sdl_mixer"
 )
 
+// alienType describes a kind of alien
+type alienType struct {
+	symbol  byte     // Symbol in level files
+	color   string   // Theme role of the color the sprite is tinted with
+	weapons []string // Projectiles the alien picks from when firing
+}
+
+// alienTypes holds all alien types by name
+var alienTypes = map[string]*alienType{
+	"grunt": {
+		symbol:  'G',
+		color:   roleGrunt,
+		weapons: []string{"straight"},
+	},
+	"zigzagger": {
+		symbol:  'Z',
+		color:   roleZigzagger,
+		weapons: []string{"zigzag", "straight"},
+	},
+	"hunter": {
+		symbol:  'H',
+		color:   roleHunter,
+		weapons: []string{"homing"},
+	},
+	"splitter": {
+		symbol:  'S',
+		color:   roleSplitter,
+		weapons: []string{"split"},
+	},
+}
+
 // alien holds the alien state
 type alien struct {
-	r *sdl.Renderer
-	t *sdl.Texture
-	x int32
-	y int32
-	w int32
-	h int32
+	r   *sdl.Renderer
+	s   *sprite
+	typ *alienType
+	x   int32
+	y   int32
+	w   int32
+	h   int32
+	row int // Grid position
+	col int
+	id  int // Broadphase ID
+
+	// Formation slot position, differs from x & y while diving
+	homeX int32
+	homeY int32
+	dive  *dive // Dive state (nil: in formation)
 }
 
 // newAlien generates a alien
-func newAlien(r *sdl.Renderer, t *sdl.Texture, x, y int32) *alien {
+func newAlien(r *sdl.Renderer, s *sprite, typ *alienType, x, y int32, row, col int) *alien {
 	a := &alien{
-		r: r,
-		t: t,
-		w: 80,
-		h: 86,
-		x: x,
-		y: y,
+		r:   r,
+		s:   s,
+		typ: typ,
+		w:   80,
+		h:   86,
+		x:   x,
+		y:   y,
+		row: row,
+		col: col,
+
+		homeX: x,
+		homeY: y,
 	}
 
 	return a
@@ -35,55 +81,106 @@ func newAlien(r *sdl.Renderer, t *sdl.Texture, x, y int32) *alien {
 
 // Draw draws the alien
 func (a *alien) Draw() {
-	a.r.Copy(a.t, nil, &sdl.Rect{X: a.x, Y: a.y, W: a.w, H: a.h})
+	c := themeColor(a.typ.color)
+	a.s.t.SetColorMod(c.R, c.G, c.B)
+	a.r.Copy(a.s.t, nil, &sdl.Rect{X: a.x, Y: a.y, W: a.w, H: a.h})
+	a.s.t.SetColorMod(0xFF, 0xFF, 0xFF)
+}
+
+// box returns the alien bounding box
+func (a *alien) box() collision.Box {
+	return collision.Box{X: a.x, Y: a.y, W: a.w, H: a.h}
 }
 
 // alienGridConfig holds the alien grid config
 type alienGridConfig struct {
-	rows        int     // Number of rows
-	cols        int     // Number of columns
-	marginRow   int     // Space between rows
-	marginCol   int     // Space between columns
-	returnPoint int32   // When to switch the x direction
-	speedMax    int     // Grid movement max speed
-	speedStep   int     // After how many drops to increase the speed
-	bulletSpeed int32   // Speed of a bullet
-	fireRate    float64 // Rate at that the aliens fire
-	stepSizeX   int32   // Horizontal step size
-	stepSizeY   int32   // Vertical step size
+	rows         int      // Number of rows
+	cols         int      // Number of columns
+	marginRow    int      // Space between rows
+	marginCol    int      // Space between columns
+	returnPoint  int32    // When to switch the x direction
+	speedMax     int      // Grid movement max speed
+	speedStep    int      // After how many drops to increase the speed
+	bulletSpeed  int32    // Speed of a bullet
+	fireRate     float64  // Rate at that the aliens fire
+	stepSizeX    int32    // Horizontal step size
+	stepSizeY    int32    // Vertical step size
+	pixelPerfect bool     // Test hits against the sprite mask instead of the bounding box
+	rowTypes     []string // Alien type of each row from the top (last value repeats)
+	layout       []string // Alien type symbols of each slot by row (empty: rows x cols of rowTypes)
+
+	maxDivers       int       // Max number of aliens diving at once
+	diveRates       []float64 // Chance per tick to start a dive by level (last value repeats)
+	diveFireRate    float64   // Chance per tick that a diving alien fires
+	diveReturnTicks int       // Duration of the flight back to the formation
 }
 
 // alienGrid holds the alien grid state
 type alienGrid struct {
 	c            *alienGridConfig
 	r            *sdl.Renderer
-	t            *sdl.Texture
-	sounds       map[string]*mix.Chunk
-	alienList    []*alien   // List of all aliens
-	alienGridPos [][]*alien // List of all alien grid positions
-	direction    int32      // direction of x movement (1: left, -1: right)
-	dropCount    int        // How often the grid moved down in y
-	speed        int        // Grid movement speed
-	moveCounter  int        // Counts how many moves have been requested
+	rng          *rand.Rand
+	s            *sprite
+	sprites      map[string]*sprite       // Sprites by path
+	weapons      map[string]*bulletConfig // Bullet configs by projectile name
+	sounds       map[string]*sound
+	alienList    []*alien      // List of all aliens
+	alienGridPos [][]*alien    // List of all alien grid positions
+	bounds       collision.Box // Rectangle around all aliens
+	formation    collision.Box // Rectangle around all formation slots
+	paths        []*divePath   // Dive paths
+	divers       int           // Number of diving aliens
+	level        int           // Level the grid has been created for
+	originX      int32         // Formation position of the top left slot
+	originY      int32
+	cellW        int32 // Slot size including margins
+	cellH        int32
+	broad        *collision.Grid // Broadphase for hit tests
+	byID         []*alien        // Aliens by broadphase ID
+	direction    int32           // direction of x movement (1: left, -1: right)
+	dropCount    int             // How often the grid moved down in y
+	speed        int             // Grid movement speed
+	moveCounter  int             // Counts how many moves have been requested
+	hooks        *scripts        // Mod scripts (nil: none)
 }
 
 // newAlienGrid creates a new alien grid
-func newAlienGrid(r *sdl.Renderer, c *alienGridConfig) (*alienGrid, error) {
-	maxX, _, _ := r.GetRendererOutputSize()
+func newAlienGrid(r *sdl.Renderer, c *alienGridConfig, rng *rand.Rand, level int) (*alienGrid, error) {
+	maxX, _ := screenSize(r)
 
 	ag := &alienGrid{
 		c:         c,
 		r:         r,
+		rng:       rng,
+		level:     level,
 		direction: 1,
 		dropCount: 0,
 		speed:     1,
+		broad:     collision.NewGrid(128),
 	}
 
 	var err error
-	ag.t, err = img.LoadTexture(ag.r, "assets/alien.png")
+	ag.s, err = loadSprite(ag.r, "assets/alien.png")
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create alien texture: %v", err)
 	}
+	ag.sprites = map[string]*sprite{"assets/alien.png": ag.s}
+
+	// Set slot types
+	slots, err := ag.c.slotTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the weapons of all types, rows of any type may drop in later
+	weapons := []string{}
+	for _, name := range alienTypeNames() {
+		weapons = append(weapons, alienTypes[name].weapons...)
+	}
+	ag.weapons, err = loadWeapons(ag.r, weapons, ag.c.bulletSpeed, ag.sprites)
+	if err != nil {
+		return nil, err
+	}
 
 	textureWidth := 80 // TODO: get this dynamically
 	textureHeight := 86
@@ -93,24 +190,34 @@ func newAlienGrid(r *sdl.Renderer, c *alienGridConfig) (*alienGrid, error) {
 	startY := 50
 	currentX := startX
 	currentY := startY
+	ag.originX, ag.originY = int32(startX), int32(startY)
+	ag.cellW = int32(textureWidth + ag.c.marginCol)
+	ag.cellH = int32(textureHeight + ag.c.marginRow)
 	ag.alienGridPos = make([][]*alien, ag.c.rows)
 	for row := 0; row < ag.c.rows; row++ {
 		ag.alienGridPos[row] = make([]*alien, ag.c.cols)
 		for col := 0; col < ag.c.cols; col++ {
-			a := newAlien(r, ag.t, int32(currentX), int32(currentY))
+			if slots[row][col] != nil {
+				a := newAlien(r, ag.s, slots[row][col], int32(currentX), int32(currentY), row, col)
+				ag.alienList = append(ag.alienList, a)
+				ag.alienGridPos[row][col] = a
+			}
 			currentX += textureWidth + ag.c.marginCol
-			ag.alienList = append(ag.alienList, a)
-			ag.alienGridPos[row][col] = a
 		}
 		currentX = startX
 		currentY += textureHeight + ag.c.marginRow
 	}
+	ag.index()
 
-	// Set sounds
-	ag.sounds = make(map[string]*mix.Chunk, 0)
-	ag.sounds["hit"], err = mix.LoadWAV("assets/sounds/alienhit.wav")
+	ag.paths, err = loadDivePaths("assets/dives.json")
 	if err != nil {
-		return nil, fmt.Errorf("couldn't load sound: %v", err)
+		return nil, err
+	}
+
+	// Set sounds
+	ag.sounds = make(map[string]*sound, 0)
+	if err := loadSound(ag.r, ag.sounds, "hit", "assets/sounds/alienhit.wav"); err != nil {
+		return nil, err
 	}
 
 	return ag, nil
@@ -121,7 +228,24 @@ func (ag *alienGrid) Draw() {
 	for _, a := range ag.alienList {
 		a.Draw()
 	}
+}
+
+// update advances the alien grid by one tick
+// Diving aliens head for the player and fire into the bullet list
+func (ag *alienGrid) update(p *player, bullets *bulletList) {
 	ag.move()
+	ag.updateDivers(bullets)
+	ag.startDive(point{x: float64(p.x + p.w/2), y: float64(p.y + p.h/2)})
+	if ag.hooks != nil {
+		for _, a := range ag.alienList {
+			ag.hooks.onMove(a)
+		}
+	}
+
+	for _, a := range ag.alienList {
+		ag.broad.Move(a.id, a.box())
+	}
+	ag.measure()
 }
 
 // move moves the alien grid left and right and down
@@ -135,7 +259,7 @@ func (ag *alienGrid) move() {
 	ag.moveCounter = 0
 
 	// Viewport && grid dimensions
-	maxX, _, _ := ag.r.GetRendererOutputSize()
+	maxX, _ := screenSize(ag.r)
 	x1, _, x2, _ := ag.getDimensions()
 
 	// Check if the grid hits the boundary
@@ -153,72 +277,205 @@ func (ag *alienGrid) move() {
 		ag.direction *= -1
 	}
 
-	// Move all aliens
+	// Move all formation slots, diving aliens catch up on their own
+	if moveY {
+		ag.originY += ag.c.stepSizeY
+	} else {
+		ag.originX += ag.direction * ag.c.stepSizeX
+	}
 	for _, a := range ag.alienList {
 		if moveY {
-			a.y += ag.c.stepSizeY
+			a.homeY += ag.c.stepSizeY
 		} else {
-			a.x += ag.direction * ag.c.stepSizeX
+			a.homeX += ag.direction * ag.c.stepSizeX
+		}
 
+		if a.dive == nil {
+			a.x, a.y = a.homeX, a.homeY
 		}
 	}
 }
 
-// getDimentsions returns the current alien grid rectangle coordinates
-func (ag *alienGrid) getDimensions() (x1, y1, x2, y2 int32) {
-	x1, y1, x2, y2 = 0, 0, 0, 0
+// addRow drops a fresh row of aliens in above the formation if there is room
+// at the top and returns true if it did
+func (ag *alienGrid) addRow(typ *alienType) bool {
+	y := ag.originY - ag.cellH
+	if y < 50 {
+		return false
+	}
 
+	// Existing rows move down by one index
 	for _, a := range ag.alienList {
-		if a.x < x1 || x1 == 0 {
-			x1 = a.x
+		a.row++
+	}
+
+	row := make([]*alien, ag.c.cols)
+	for col := range row {
+		row[col] = newAlien(ag.r, ag.s, typ, ag.originX+int32(col)*ag.cellW, y, 0, col)
+		ag.alienList = append(ag.alienList, row[col])
+		ag.track(row[col])
+	}
+	ag.alienGridPos = append([][]*alien{row}, ag.alienGridPos...)
+	ag.originY = y
+
+	ag.measure()
+
+	return true
+}
+
+// slotTypes returns the alien type of each grid slot by row, empty slots are
+// nil
+func (c *alienGridConfig) slotTypes() ([][]*alienType, error) {
+	slots := make([][]*alienType, c.rows)
+	for row := range slots {
+		slots[row] = make([]*alienType, c.cols)
+
+		// Layouts place aliens slot by slot
+		if len(c.layout) > 0 {
+			for col := 0; col < c.cols && row < len(c.layout) && col < len(c.layout[row]); col++ {
+				sym := c.layout[row][col]
+				if sym == levelEmpty {
+					continue
+				}
+
+				_, typ := alienTypeBySymbol(sym)
+				if typ == nil {
+					return nil, fmt.Errorf("unknown alien symbol %c", sym)
+				}
+				slots[row][col] = typ
+			}
+			continue
 		}
-		if a.x+a.w > x2 {
-			x2 = a.x + a.w
+
+		name := "grunt"
+		if len(c.rowTypes) > 0 {
+			name = c.rowTypes[min(row, len(c.rowTypes)-1)]
 		}
-		if a.y < y1 || y1 == 0 {
-			y1 = a.y
+
+		typ, ok := alienTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown alien type %s", name)
 		}
-		if a.y+a.h > y2 {
-			y2 = a.y + a.h
+		for col := range slots[row] {
+			slots[row][col] = typ
 		}
 	}
 
-	return
+	return slots, nil
+}
+
+// alienTypeBySymbol returns the name and the type of the alien with a level
+// file symbol or nil if there is none
+func alienTypeBySymbol(sym byte) (string, *alienType) {
+	for name, typ := range alienTypes {
+		if typ.symbol == sym {
+			return name, typ
+		}
+	}
+
+	return "", nil
+}
+
+// alienTypeNames returns the names of all alien types in a stable order
+func alienTypeNames() []string {
+	names := []string{}
+	for name := range alienTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// index rebuilds the broadphase from the alien list, only needed when the
+// whole list is replaced
+func (ag *alienGrid) index() {
+	ag.broad.Reset()
+	ag.byID = ag.byID[:0]
+	for _, a := range ag.alienList {
+		ag.track(a)
+	}
+	ag.measure()
+}
+
+// track adds an alien to the broadphase
+func (ag *alienGrid) track(a *alien) {
+	a.id = ag.broad.Insert(a.box())
+	for len(ag.byID) <= a.id {
+		ag.byID = append(ag.byID, nil)
+	}
+	ag.byID[a.id] = a
+}
+
+// measure updates the grid bounds after aliens have moved or have been
+// removed
+func (ag *alienGrid) measure() {
+	ag.bounds = collision.Box{}
+	ag.formation = collision.Box{}
+
+	for _, a := range ag.alienList {
+		ag.bounds = ag.bounds.Union(a.box())
+		ag.formation = ag.formation.Union(collision.Box{X: a.homeX, Y: a.homeY, W: a.w, H: a.h})
+	}
+}
+
+// getDimentsions returns the current alien formation rectangle coordinates
+func (ag *alienGrid) getDimensions() (x1, y1, x2, y2 int32) {
+	b := ag.formation
+
+	return b.X, b.Y, b.X + b.W, b.Y + b.H
 }
 
 // testHit checks if a bullet has hit an alien in the grid
 func (ag *alienGrid) testHit(bl *bulletList) (bool, int) {
-	x1, y1, x2, _ := ag.getDimensions()
-
 	for _, b := range *bl {
-		// Exit if bullet is beyond grid dimensions
-		if b.x < x1 || b.x+b.w > x2 || b.y+b.h < y1 {
+		// Skip bullets beyond grid dimensions
+		if !ag.bounds.Intersects(b.box()) {
 			continue
 		}
 
 		// Check if alien has been hit
-		for _, a := range ag.alienList {
-			if a.y+a.h < b.y || a.x > b.x+b.w || a.x+a.w < b.x {
-				continue
+		var hit *alien
+		ag.broad.Query(b.box(), func(id int) bool {
+			a := ag.byID[id]
+			if !collision.Overlaps(a.box(), ag.mask(a), b.box(), nil) {
+				return true
 			}
 
-			// Hit detected: remove alien & bullet
-			ag.remove(a)
-			bl.remove(b)
+			hit = a
+			return false
+		})
+		if hit == nil {
+			continue
+		}
 
-			ag.sounds["hit"].Play(0, 0)
+		// Hit detected: remove alien & bullet
+		ag.hooks.onHit(hit)
+		ag.remove(hit)
+		bl.remove(b)
 
-			return true, len(ag.alienList)
-		}
+		ag.sounds["hit"].Play(0, 0)
+
+		return true, len(ag.alienList)
 	}
 
 	return false, len(ag.alienList)
 }
 
+// mask returns the collision mask of an alien or nil if hits are tested
+// against the bounding box
+func (ag *alienGrid) mask(a *alien) *collision.Mask {
+	if !ag.c.pixelPerfect {
+		return nil
+	}
+
+	return a.s.m
+}
+
 // testBoundary checks if the aliens have reached the ground
 func (ag *alienGrid) testBoundary() bool {
 	_, _, _, y := ag.getDimensions()
-	_, maxY, _ := ag.r.GetRendererOutputSize()
+	_, maxY := screenSize(ag.r)
 
 	return y >= int32(maxY)
 }
@@ -241,12 +498,12 @@ func (ag *alienGrid) testPlayerCollission(p *player) (collission bool) {
 
 		a := bottomAliens[c]
 
-		// Test if alien is higher than player
-		if a.y+a.h < p.y {
+		// Test if alien slot is higher than player
+		if a.homeY+a.h < p.y {
 			continue
 		}
 
-		if a.x >= p.x && a.x+a.w <= p.x+p.w {
+		if a.homeX >= p.x && a.homeX+a.w <= p.x+p.w {
 			return true
 		}
 	}
@@ -254,60 +511,100 @@ func (ag *alienGrid) testPlayerCollission(p *player) (collission bool) {
 	return
 }
 
+// testDiverCollision checks if a diving alien has crashed into the player
+// Both are hit, this returns true if the player is dead. Invulnerable players
+// are passed through.
+func (ag *alienGrid) testDiverCollision(p *player) (dead bool) {
+	if !p.vulnerable() {
+		return
+	}
+
+	for _, a := range ag.alienList {
+		if a.dive == nil {
+			continue
+		}
+
+		box, mask := p.hitBox()
+		if !collision.Overlaps(a.box(), ag.mask(a), box, mask) {
+			continue
+		}
+
+		ag.remove(a)
+		ag.sounds["hit"].Play(0, 0)
+
+		return p.hit()
+	}
+
+	return
+}
+
 // remove removes an alien from the grid
 func (ag *alienGrid) remove(a *alien) {
-	// Remove alien from alien list
-	tmpAl := []*alien{}
+	// Remove alien from alien list in place
+	n := 0
 	for _, ta := range ag.alienList {
 		if ta != a {
-			tmpAl = append(tmpAl, ta)
+			ag.alienList[n] = ta
+			n++
 		}
 	}
-	ag.alienList = tmpAl
+	ag.alienList[n] = nil
+	ag.alienList = ag.alienList[:n]
 
-	// Remove alien from alien grid position
-	for r := range ag.alienGridPos {
-		for c, ta := range ag.alienGridPos[r] {
-			if ta == a {
-				ag.alienGridPos[r][c] = nil
-				return
-			}
-		}
+	if a.dive != nil {
+		ag.divers--
 	}
+
+	// Remove alien from alien grid position
+	ag.alienGridPos[a.row][a.col] = nil
+
+	ag.broad.Remove(a.id)
+	ag.byID[a.id] = nil
+	ag.measure()
 }
 
 // fire randomly fires a bullets
 func (ag *alienGrid) fire(bullets *bulletList) {
-	if rand.Float64() > ag.c.fireRate {
+	if ag.rng.Float64() > ag.c.fireRate {
 		return
 	}
 
 	// Lowest row of aliens fires
 	bottomAliens := ag.bottomAliens()
 	for c := 0; c < ag.c.cols; c++ {
-		if rand.Float64() > ag.c.fireRate {
+		if ag.rng.Float64() > ag.c.fireRate {
 			continue
 		}
 
 		if bottomAliens[c] != nil {
-			a := bottomAliens[c]
-			newBullet(
-				ag.r,
-				bullets,
-				a.x+a.w/2,
-				a.y+a.h,
-				&bulletConfig{
-					speed:     ag.c.bulletSpeed,
-					direction: 1,
-					colorR:    0xF6,
-					colorG:    0x25,
-					colorB:    0x9B,
-				},
-			)
+			ag.fireFrom(bottomAliens[c], bullets)
 		}
 	}
 }
 
+// fireFrom fires one of the alien's weapons
+func (ag *alienGrid) fireFrom(a *alien, bullets *bulletList) {
+	name, ok := ag.hooks.onFire(a, a.typ.weapons[ag.rng.Intn(len(a.typ.weapons))])
+	if !ok {
+		return
+	}
+
+	// Scripts may only pick weapons the grid has loaded
+	c := ag.weapons[name]
+	if c == nil {
+		ag.hooks.fail("onFire", fmt.Errorf("unknown weapon %s", name))
+		return
+	}
+
+	newBullet(
+		ag.r,
+		bullets,
+		a.x+a.w/2-c.w/2,
+		a.y+a.h,
+		c,
+	)
+}
+
 // bottomAliens returns a map of aliens that are the lowest of its column
 func (ag *alienGrid) bottomAliens() map[int]*alien {
 	bottomAliens := make(map[int]*alien, ag.c.cols)
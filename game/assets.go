@@ -0,0 +1,171 @@
+package game
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/MichaelThessel/spacee/app"
+	"github.com/veandco/go-sdl2/sdl"
+	mix "github.com/veandco/go-sdl2/sdl_mixer"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// liveResources counts the textures, sounds & fonts that haven't been freed
+var liveResources atomic.Int64
+
+// assetCaches holds the shared sprites & sounds by renderer, each app has one
+var assetCaches = map[*sdl.Renderer]*assetCache{}
+
+// assetCache holds the sprites & sounds loaded for a renderer by file
+// Entities share them, they live until the app is destroyed, so replay
+// snapshots and dev mode reloads can refer to them.
+type assetCache struct {
+	sprites map[string]*sprite
+	sounds  map[string]*sound
+}
+
+// sound holds a loaded sound file
+type sound struct {
+	c *mix.Chunk
+}
+
+// Play plays the sound
+func (s *sound) Play(channel, loops int) (int, error) {
+	return s.c.Play(channel, loops)
+}
+
+// useAssets sets up the asset cache of an app, it is freed when the app is
+// destroyed
+func useAssets(a *app.App) {
+	r := a.GetRenderer()
+	if _, ok := assetCaches[r]; ok {
+		return
+	}
+
+	ac := assetsFor(r)
+	a.Own(app.AppScope, func() {
+		ac.free()
+		delete(assetCaches, r)
+	})
+}
+
+// assetsFor returns the asset cache of a renderer
+func assetsFor(r *sdl.Renderer) *assetCache {
+	ac, ok := assetCaches[r]
+	if !ok {
+		ac = &assetCache{
+			sprites: map[string]*sprite{},
+			sounds:  map[string]*sound{},
+		}
+		assetCaches[r] = ac
+	}
+
+	return ac
+}
+
+// free frees all cached assets
+func (ac *assetCache) free() {
+	for path, s := range ac.sprites {
+		destroyTexture(s.t)
+		delete(ac.sprites, path)
+	}
+	for path, s := range ac.sounds {
+		freeChunk(s.c)
+		delete(ac.sounds, path)
+	}
+}
+
+// loadSound loads a sound file into sounds[key], sounds of the same file are
+// shared
+func loadSound(r *sdl.Renderer, sounds map[string]*sound, key, path string) error {
+	ac := assetsFor(r)
+	path = filepath.Clean(path)
+
+	if s, ok := ac.sounds[path]; ok {
+		sounds[key] = s
+		return nil
+	}
+
+	c, err := loadChunk(path)
+	if err != nil {
+		return err
+	}
+	ac.sounds[path] = &sound{c: c}
+	sounds[key] = ac.sounds[path]
+
+	return nil
+}
+
+// reload reloads a changed file into all places it is used and returns false
+// if the file isn't a loaded asset
+func (ac *assetCache) reload(r *sdl.Renderer, path string) (bool, error) {
+	path = filepath.Clean(path)
+
+	if s, ok := ac.sprites[path]; ok {
+		// Sprites are shared, replacing the content updates every user
+		ns, err := readSprite(r, path)
+		if err != nil {
+			return true, err
+		}
+		destroyTexture(s.t)
+		*s = *ns
+		return true, nil
+	}
+
+	if s, ok := ac.sounds[path]; ok {
+		c, err := loadChunk(path)
+		if err != nil {
+			return true, err
+		}
+		freeChunk(s.c)
+		s.c = c
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// loadChunk loads a sound file
+func loadChunk(path string) (*mix.Chunk, error) {
+	c, err := mix.LoadWAV(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load sound: %v", err)
+	}
+	liveResources.Add(1)
+
+	return c, nil
+}
+
+// freeChunk frees a sound
+func freeChunk(c *mix.Chunk) {
+	c.Free()
+	liveResources.Add(-1)
+}
+
+// destroyTexture destroys a texture loaded by readSprite
+func destroyTexture(t *sdl.Texture) {
+	t.Destroy()
+	liveResources.Add(-1)
+}
+
+// openFont opens the font of the active theme in a size
+func openFont(size int) (*ttf.Font, error) {
+	f, err := ttf.OpenFont(activeTheme.font, size)
+	if err != nil {
+		return nil, fmt.Errorf("could not load font: %v", err)
+	}
+	liveResources.Add(1)
+
+	return f, nil
+}
+
+// closeFont closes a font opened by openFont, nil fonts are skipped so
+// partially set up screens can be closed
+func closeFont(f *ttf.Font) {
+	if f == nil {
+		return
+	}
+	f.Close()
+	liveResources.Add(-1)
+}
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// assists holds the accessibility assists of a game, they are kept with the
+// config so replays play the same and with each score so scores stay
+// comparable
+type assists struct {
+	Speed        int   `json:"speed,omitempty"`        // Game speed in percent (0: full speed)
+	AutoFire     bool  `json:"autoFire,omitempty"`     // Fire whenever the player can
+	HitMargin    int32 `json:"hitMargin,omitempty"`    // Pixels the player hitbox shrinks by on each side
+	HighContrast bool  `json:"highContrast,omitempty"` // Outline the player & enlarge bullets
+}
+
+// gameSpeeds holds the selectable game speeds in percent
+var gameSpeeds = []int{100, 75, 50}
+
+// hitMargins holds the selectable hitbox forgiveness margins
+var hitMargins = []int32{0, 4, 8, 12}
+
+// speed returns the game speed in percent
+func (a assists) speed() int {
+	if a.Speed <= 0 || a.Speed > 100 {
+		return 100
+	}
+
+	return a.Speed
+}
+
+// String returns the active assists, empty if there are none
+func (a assists) String() string {
+	var active []string
+	if a.speed() < 100 {
+		active = append(active, fmt.Sprintf("SPEED %d%%", a.speed()))
+	}
+	if a.AutoFire {
+		active = append(active, "AUTO-FIRE")
+	}
+	if a.HitMargin > 0 {
+		active = append(active, fmt.Sprintf("HITBOX -%d", a.HitMargin))
+	}
+	if a.HighContrast {
+		active = append(active, "HIGH CONTRAST")
+	}
+
+	return strings.Join(active, " ")
+}
+
+// advance returns true if the game ticks this update, slower games skip
+// updates evenly
+func (g *Game) advance() bool {
+	g.slow += g.c.assists.speed()
+	if g.slow < 100 {
+		return false
+	}
+	g.slow -= 100
+
+	return true
+}
+
+// drawContrast outlines the player and draws the bullets larger on top of the
+// sprites
+func (g *Game) drawContrast() {
+	if !g.c.assists.HighContrast {
+		return
+	}
+	r := g.a.GetRenderer()
+
+	if g.p.respawn == 0 {
+		c := themeColor(roleHUDText)
+		r.SetDrawColor(c.R, c.G, c.B, 0xFF)
+		r.DrawRect(&sdl.Rect{X: g.p.x - 2, Y: g.p.y - 2, W: g.p.w + 4, H: g.p.h + 4})
+	}
+
+	drawBullets := func(bl bulletList, c sdl.Color) {
+		r.SetDrawColor(c.R, c.G, c.B, 0xFF)
+		for _, b := range bl {
+			r.FillRect(&sdl.Rect{X: b.x - 2, Y: b.y - 2, W: b.w + 4, H: b.h + 4})
+		}
+	}
+	drawBullets(*g.pbl, themeColor(rolePlayerBullet))
+	drawBullets(*g.abl, themeColor(roleEnemyBullet))
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/MichaelThessel/spacee/app"
+)
+
+// batch holds the state of a batch run
+type batch struct {
+	games  int
+	played int
+	w      *csv.Writer
+	err    error
+}
+
+// Batch plays a number of games with a bot and writes one CSV record per
+// game. Game i is seeded with the options seed + i.
+// Games that last maxTicks are cut off and recorded as timeouts (0: no limit).
+func Batch(a *app.App, o *Options, games, maxTicks int, w io.Writer) error {
+	if o.Bot == "" {
+		return errors.New("batch mode needs a bot")
+	}
+	o.Scene = scenePlay
+
+	b := &batch{
+		games: games,
+		w:     csv.NewWriter(w),
+	}
+	b.w.Write([]string{
+		"game", "seed", "bot", "mode", "score", "level", "ticks", "result",
+		"difficulty", "rows", "cols", "speedMax", "speedStep", "bulletSpeed", "fireRate", "stepSizeY", "lifes",
+	})
+
+	g, err := New(a, o)
+	if err != nil {
+		return err
+	}
+	g.maxTicks = maxTicks
+	g.onGameOver = func() { b.record(g) }
+
+	runErr := run(a)
+
+	b.w.Flush()
+	if runErr != nil {
+		return fmt.Errorf("batch stopped after %d of %d games: %v", b.played, b.games, runErr)
+	}
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.w.Error()
+}
+
+// run runs the app and returns an error if it stopped on a failure
+func run(a *app.App) error {
+	code := a.Run()
+	if code == 0 {
+		return nil
+	}
+	if err := a.Err(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("app stopped with exit code %d", code)
+}
+
+// record records the result & config of the current game and starts the
+// next one
+func (b *batch) record(g *Game) {
+	result := "over"
+	if g.timedOut {
+		result = "timeout"
+	}
+
+	b.w.Write([]string{
+		strconv.Itoa(b.played),
+		strconv.FormatInt(g.seed, 10),
+		g.o.Bot,
+		g.mode.name,
+		strconv.Itoa(g.score),
+		strconv.Itoa(g.level),
+		strconv.Itoa(g.ticks),
+		result,
+		g.c.preset().name,
+		strconv.Itoa(g.c.agc.rows),
+		strconv.Itoa(g.c.agc.cols),
+		strconv.Itoa(g.c.agc.speedMax),
+		strconv.Itoa(g.c.agc.speedStep),
+		strconv.Itoa(int(g.c.agc.bulletSpeed)),
+		strconv.FormatFloat(g.c.agc.fireRate, 'g', -1, 64),
+		strconv.Itoa(int(g.c.agc.stepSizeY)),
+		strconv.Itoa(g.c.pc.lifes),
+	})
+
+	b.played++
+	if b.played == b.games {
+		g.a.ClearCallbacks()
+		g.a.Quit()
+		return
+	}
+
+	g.seed = g.o.Seed + int64(b.played)
+
+	if b.err = g.switchScene(scenePlay); b.err != nil {
+		g.a.ClearCallbacks()
+		g.a.Quit()
+	}
+}
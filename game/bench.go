@@ -0,0 +1,46 @@
+package game
+
+import (
+	"github.com/MichaelThessel/spacee/app"
+)
+
+// benchBot plays the benchmark session
+const benchBot = "track"
+
+// Benchmark plays a scripted session of a number of frames and returns the
+// frame times
+// The bot plays classic games with a fixed seed, lost games restart, so runs
+// render the same frames and can be compared with each other.
+func Benchmark(a *app.App, o *Options, frames int) (app.FrameReport, error) {
+	o.Scene = scenePlay
+	o.Mode = modeClassic
+	o.Bot = benchBot
+	o.Controller = nil
+	if o.Seed == 0 {
+		o.Seed = 1
+	}
+
+	g, err := New(a, o)
+	if err != nil {
+		return app.FrameReport{}, err
+	}
+
+	played := 0
+	g.onGameOver = func() {
+		played++
+		g.seed = g.o.Seed + int64(played)
+		if err = g.switchScene(scenePlay); err != nil {
+			g.a.ClearCallbacks()
+		}
+	}
+
+	a.RunFrames(frames)
+	if err != nil {
+		return app.FrameReport{}, err
+	}
+	if err := a.Err(); err != nil {
+		return app.FrameReport{}, err
+	}
+
+	return a.FrameReport(), nil
+}
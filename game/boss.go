@@ -0,0 +1,255 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/MichaelThessel/spacee/collision"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// bossConfig holds the boss configuration
+type bossConfig struct {
+	every  int   // Boss wave every n levels (0: never)
+	hp     int   // Hit points of the first boss
+	hpStep int   // Additional hit points for each following boss
+	speed  int32 // Horizontal speed
+	points int   // Points for defeating a boss
+}
+
+// bossPhase describes how the boss behaves at a health level
+type bossPhase struct {
+	below    float64  // Phase starts once the health fraction drops below
+	speed    float64  // Speed multiplier
+	bob      float64  // Vertical movement amplitude
+	fireRate float64  // Chance per tick to fire a volley
+	volley   int      // Bullets per volley
+	weapons  []string // Projectiles the boss picks from
+}
+
+// bossPhases holds the boss phases ordered by health
+var bossPhases = []bossPhase{
+	{below: 1.01, speed: 1, fireRate: 0.04, volley: 3, weapons: []string{"straight"}},
+	{below: 0.66, speed: 1.5, bob: 20, fireRate: 0.05, volley: 4, weapons: []string{"zigzag", "straight"}},
+	{below: 0.33, speed: 2, bob: 40, fireRate: 0.07, volley: 5, weapons: []string{"homing", "split"}},
+}
+
+// bossWeakPoints holds the areas that damage the boss as fractions of its size
+// Hits anywhere else bounce off.
+var bossWeakPoints = []struct{ x, y, w, h float64 }{
+	{x: 0.22, y: 0.30, w: 0.16, h: 0.14},
+	{x: 0.62, y: 0.30, w: 0.16, h: 0.14},
+	{x: 0.42, y: 0.62, w: 0.16, h: 0.12},
+}
+
+// boss holds the boss state
+type boss struct {
+	c         *bossConfig
+	r         *sdl.Renderer
+	rng       *rand.Rand
+	s1        *sprite // Animation frames
+	s2        *sprite
+	weapons   map[string]*bulletConfig
+	sounds    map[string]*sound
+	x         int32
+	y         int32
+	baseY     int32
+	w         int32
+	h         int32
+	hp        int
+	maxHP     int
+	direction int32
+	ticks     int
+	flash     int // Ticks the weak points stay lit after a hit
+
+	pixelPerfect bool // Test hits against the sprite mask instead of the bounding box
+}
+
+// newBoss creates the nth boss
+func newBoss(r *sdl.Renderer, c *bossConfig, bulletSpeed int32, pixelPerfect bool, rng *rand.Rand, n int) (*boss, error) {
+	maxX, _ := screenSize(r)
+
+	b := &boss{
+		c:         c,
+		r:         r,
+		rng:       rng,
+		w:         240,
+		h:         257,
+		y:         60,
+		baseY:     60,
+		maxHP:     c.hp + (n-1)*c.hpStep,
+		direction: 1,
+
+		pixelPerfect: pixelPerfect,
+	}
+	b.hp = b.maxHP
+	b.x = int32(maxX)/2 - b.w/2
+
+	var err error
+	b.s1, err = loadSprite(r, "assets/alien_l1.png")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create boss texture 1: %v", err)
+	}
+	b.s2, err = loadSprite(r, "assets/alien_l2.png")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create boss texture 2: %v", err)
+	}
+
+	weapons := []string{}
+	for _, p := range bossPhases {
+		weapons = append(weapons, p.weapons...)
+	}
+	b.weapons, err = loadWeapons(r, weapons, bulletSpeed, map[string]*sprite{})
+	if err != nil {
+		return nil, err
+	}
+
+	b.sounds = make(map[string]*sound, 0)
+	if err := loadSound(r, b.sounds, "hit", "assets/sounds/alienhit.wav"); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Draw draws the boss and its weak points
+func (b *boss) Draw() {
+	s := b.frame()
+	b.r.Copy(s.t, nil, &sdl.Rect{X: b.x, Y: b.y, W: b.w, H: b.h})
+
+	// Weak points pulse and light up when hit
+	alpha := uint8(0x60 + 0x40*math.Sin(float64(b.ticks)/4))
+	if b.flash > 0 {
+		alpha = 0xFF
+	}
+	b.r.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	c := themeColor(roleWeakPoint)
+	b.r.SetDrawColor(c.R, c.G, c.B, alpha)
+	for _, wp := range b.weakPoints() {
+		b.r.FillRect(&sdl.Rect{X: wp.X, Y: wp.Y, W: wp.W, H: wp.H})
+	}
+	b.r.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+}
+
+// box returns the boss bounding box
+func (b *boss) box() collision.Box {
+	return collision.Box{X: b.x, Y: b.y, W: b.w, H: b.h}
+}
+
+// weakPoints returns the weak point boxes in screen coordinates
+func (b *boss) weakPoints() []collision.Box {
+	boxes := make([]collision.Box, len(bossWeakPoints))
+	for i, wp := range bossWeakPoints {
+		boxes[i] = collision.Box{
+			X: b.x + int32(wp.x*float64(b.w)),
+			Y: b.y + int32(wp.y*float64(b.h)),
+			W: int32(wp.w * float64(b.w)),
+			H: int32(wp.h * float64(b.h)),
+		}
+	}
+
+	return boxes
+}
+
+// phase returns the phase for the current health
+func (b *boss) phase() *bossPhase {
+	health := float64(b.hp) / float64(b.maxHP)
+
+	p := &bossPhases[0]
+	for i := range bossPhases {
+		if health < bossPhases[i].below {
+			p = &bossPhases[i]
+		}
+	}
+
+	return p
+}
+
+// update moves the boss and lets it fire
+func (b *boss) update(bullets *bulletList) {
+	maxX, _ := screenSize(b.r)
+	p := b.phase()
+
+	b.ticks++
+	if b.flash > 0 {
+		b.flash--
+	}
+
+	// Sweep left and right, bob up and down in later phases
+	b.x += int32(float64(b.c.speed*b.direction) * p.speed)
+	if b.x < 0 || b.x+b.w > int32(maxX) {
+		b.direction *= -1
+		b.x = max(0, min(b.x, int32(maxX)-b.w))
+	}
+	b.y = b.baseY + int32(p.bob*math.Sin(float64(b.ticks)/15))
+
+	if b.rng.Float64() > p.fireRate {
+		return
+	}
+
+	// Fire a volley spread over the bottom of the boss
+	for i := 0; i < p.volley; i++ {
+		c := b.weapons[p.weapons[b.rng.Intn(len(p.weapons))]]
+		x := b.x + b.w*int32(i+1)/int32(p.volley+1)
+		newBullet(b.r, bullets, x-c.w/2, b.y+b.h-20, c)
+	}
+}
+
+// frame returns the animation frame the boss is drawn with
+func (b *boss) frame() *sprite {
+	if b.ticks%20 >= 10 {
+		return b.s2
+	}
+
+	return b.s1
+}
+
+// mask returns the collision mask of the current frame or nil if hits are
+// tested against the bounding box
+func (b *boss) mask() *collision.Mask {
+	if !b.pixelPerfect {
+		return nil
+	}
+
+	return b.frame().m
+}
+
+// testHit checks if player bullets have hit the boss and returns the points
+// scored
+func (b *boss) testHit(bl *bulletList) (points int) {
+	for i := 0; i < len(*bl); i++ {
+		bt := (*bl)[i]
+
+		if !collision.Overlaps(b.box(), b.mask(), bt.box(), nil) {
+			continue
+		}
+
+		// The removal shifts the next bullet into this slot
+		bl.remove(bt)
+		i--
+
+		for _, wp := range b.weakPoints() {
+			if !wp.Intersects(bt.box()) || b.hp == 0 {
+				continue
+			}
+
+			b.hp--
+			b.flash = 5
+			b.sounds["hit"].Play(0, 0)
+			points += 10
+
+			if b.hp == 0 {
+				points += b.c.points
+			}
+			break
+		}
+	}
+
+	return
+}
+
+// defeated checks if the boss is out of hit points
+func (b *boss) defeated() bool {
+	return b.hp == 0
+}
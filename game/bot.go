@@ -0,0 +1,138 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+// bots holds constructors for all scripted bots by name
+var bots = map[string]func() Controller{
+	"dodge": func() Controller { return &dodgeBot{} },
+	"track": func() Controller { return &trackBot{} },
+}
+
+// NewBot returns the bot with the given name
+func NewBot(name string) (Controller, error) {
+	b, ok := bots[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown bot %s (available: %v)", name, BotNames())
+	}
+
+	return b(), nil
+}
+
+// BotNames returns the names of all bots
+func BotNames() []string {
+	names := []string{}
+	for name := range bots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// dodgeBot stays in place, fires and steps out of the way of alien bullets
+type dodgeBot struct{}
+
+// Act implements Controller
+func (b *dodgeBot) Act(w *World) Action {
+	if a, ok := dodge(w); ok {
+		return a
+	}
+
+	return Action{Fire: true}
+}
+
+// trackBot moves below the nearest alien of the lowest row and fires at it
+// while dodging alien bullets
+type trackBot struct{}
+
+// Act implements Controller
+func (b *trackBot) Act(w *World) Action {
+	if a, ok := dodge(w); ok {
+		return a
+	}
+
+	target, ok := nearestBottomAlien(w)
+	if !ok {
+		return Action{}
+	}
+
+	dx := target.X + target.W/2 - (w.Player.X + w.Player.W/2)
+	switch {
+	case dx < -w.PlayerStep/2:
+		return Action{Left: true}
+	case dx > w.PlayerStep/2:
+		return Action{Right: true}
+	default:
+		return Action{Fire: true}
+	}
+}
+
+// dodge returns a move out of the way of alien bullets if the player is
+// threatened
+func dodge(w *World) (Action, bool) {
+	if !threatened(w, w.Player.X) {
+		return Action{}, false
+	}
+
+	left := w.Player.X - w.PlayerStep
+	right := w.Player.X + w.PlayerStep
+	leftOk := left >= 0 && !threatened(w, left)
+	rightOk := right+w.Player.W <= w.Width && !threatened(w, right)
+
+	// Prefer moving towards the center to keep escape routes open
+	towardsLeft := w.Player.X+w.Player.W/2 > w.Width/2
+	switch {
+	case leftOk && (towardsLeft || !rightOk):
+		return Action{Left: true}, true
+	case rightOk:
+		return Action{Right: true}, true
+	default:
+		return Action{}, false
+	}
+}
+
+// threatened checks if an alien bullet will soon hit the player at position x
+func threatened(w *World, x int32) bool {
+	danger := w.Player.H * 4
+
+	for _, b := range w.AlienBullets {
+		if b.X+b.W < x || b.X > x+w.Player.W {
+			continue
+		}
+		if b.Y+b.H >= w.Player.Y-danger {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nearestBottomAlien returns the alien of the lowest row that is closest to
+// the player
+func nearestBottomAlien(w *World) (Rect, bool) {
+	var target Rect
+	found := false
+	for _, a := range w.Aliens {
+		switch {
+		case !found, a.Y > target.Y:
+			target = a
+		case a.Y == target.Y && abs(a.X-w.Player.X) < abs(target.X-w.Player.X):
+			target = a
+		}
+		found = true
+	}
+
+	return target, found
+}
+
+// abs returns the absolute value of x
+func abs(x int32) int32 {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
@@ -1,60 +1,107 @@
 package game
 
-import "github.com/veandco/go-sdl2/sdl"
+import (
+	"math"
+
+	"github.com/MichaelThessel/spacee/collision"
+	"github.com/veandco/go-sdl2/sdl"
+)
 
 // bulletConfig holds bullet configuration
 type bulletConfig struct {
 	speed     int32
-	direction int32 // -1 up 1 down
-	colorR    uint8
-	colorG    uint8
-	colorB    uint8
+	direction int32  // -1 up 1 down
+	color     string // Theme role of the color
+	w         int32  // Hitbox size (0: default)
+	h         int32
+	s         *sprite        // Sprite drawn instead of a rectangle (optional)
+	behavior  bulletBehavior // Movement pattern (nil: straight)
 }
 
 // bullet holds bullet state information
 type bullet struct {
-	c *bulletConfig
-	r *sdl.Renderer
-	x int32
-	y int32
-	w int32
-	h int32
+	c   *bulletConfig
+	r   *sdl.Renderer
+	x   int32
+	y   int32
+	w   int32
+	h   int32
+	fx  float64 // Precise position
+	fy  float64
+	vx  float64 // Horizontal velocity
+	ox  float64 // Horizontal launch position
+	age int     // Ticks since launch
 }
 
 // newBullet renerates a new bullet and adds it to the bullet list
-func newBullet(r *sdl.Renderer, bl *bulletList, x, y int32, c *bulletConfig) {
-	b := &bullet{
-		r: r,
-		c: c,
-		x: x,
-		y: y,
-		w: 7,
-		h: 9,
-	}
+func newBullet(r *sdl.Renderer, bl *bulletList, x, y int32, c *bulletConfig) *bullet {
+	b := makeBullet(r, x, y, c)
 
 	*bl = append(*bl, b)
 
-	b.Draw()
+	return b
+}
 
+// makeBullet generates a new bullet
+func makeBullet(r *sdl.Renderer, x, y int32, c *bulletConfig) *bullet {
+	b := &bullet{
+		r:  r,
+		c:  c,
+		x:  x,
+		y:  y,
+		w:  7,
+		h:  9,
+		fx: float64(x),
+		fy: float64(y),
+		ox: float64(x),
+	}
+
+	if c.w > 0 && c.h > 0 {
+		b.w, b.h = c.w, c.h
+	}
+
+	return b
 }
 
 // Draw an individual bullet
 func (b *bullet) Draw() {
-	b.r.SetDrawColor(b.c.colorR, b.c.colorG, b.c.colorB, 0xFF)
+	if b.c.s != nil {
+		b.r.Copy(b.c.s.t, nil, &sdl.Rect{X: b.x, Y: b.y, W: b.w, H: b.h})
+		return
+	}
+
+	c := themeColor(b.c.color)
+	b.r.SetDrawColor(c.R, c.G, c.B, 0xFF)
 
 	b.r.FillRect(
 		&sdl.Rect{X: b.x, Y: b.y, W: b.w, H: b.h},
 	)
 }
 
-// Update updates a bullets position
-// This will return false if the bullet is out of bounds
-func (b *bullet) Update() bool {
-	_, maxY, _ := b.r.GetRendererOutputSize()
+// box returns the bullet bounding box
+func (b *bullet) box() collision.Box {
+	return collision.Box{X: b.x, Y: b.y, W: b.w, H: b.h}
+}
+
+// Update updates a bullets position, target is the position homing bullets
+// steer towards (optional)
+// This will return false if the bullet is out of bounds or gone and any
+// bullets it spawned
+func (b *bullet) Update(target *point) (bool, []*bullet) {
+	maxX, maxY := screenSize(b.r)
+
+	b.age++
+
+	behavior := b.c.behavior
+	if behavior == nil {
+		behavior = straight{}
+	}
+	alive, spawn := behavior.move(b, target)
 
-	b.y += b.c.direction * b.c.speed
+	b.x = int32(math.Round(b.fx))
+	b.y = int32(math.Round(b.fy))
 
-	return !(b.y < 0 || b.y > int32(maxY))
+	return alive && !(b.y < 0 || b.y > int32(maxY) || b.x+b.w < 0 || b.x > int32(maxX)), spawn
 }
 
 // Holds all bullets currently on the screen
@@ -62,24 +109,40 @@ type bulletList []*bullet
 
 // Draw renders all existing bullets
 func (bl *bulletList) Draw() {
-	tmpBl := bulletList{}
 	for _, b := range *bl {
-		if b.Update() {
-			b.Draw()
-			tmpBl = append(tmpBl, b)
+		b.Draw()
+	}
+}
+
+// update moves all bullets, drops the ones that are out of bounds and adds
+// the ones that have been spawned
+func (bl *bulletList) update(target *point) {
+	l := *bl
+	n := 0
+	var spawned []*bullet
+	for _, b := range l {
+		alive, spawn := b.Update(target)
+		spawned = append(spawned, spawn...)
+		if alive {
+			l[n] = b
+			n++
 		}
 	}
-	*bl = tmpBl
+	for i := n; i < len(l); i++ {
+		l[i] = nil
+	}
+	*bl = append(l[:n], spawned...)
 }
 
-// remove removes a bullet from the bullet list
+// remove removes a bullet from the bullet list in place
 func (bl *bulletList) remove(b *bullet) {
-	tmpBl := bulletList{}
-	for _, tb := range *bl {
-		if tb != b {
-			tmpBl = append(tmpBl, tb)
+	l := *bl
+	for i, tb := range l {
+		if tb == b {
+			copy(l[i:], l[i+1:])
+			l[len(l)-1] = nil
+			*bl = l[:len(l)-1]
+			return
 		}
 	}
-
-	*bl = tmpBl
 }
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"github.com/MichaelThessel/spacee/collision"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+const (
+	// Bunker size in cells
+	bunkerRows = 8
+	bunkerCols = 12
+	bunkerCell = 8 // Cell size in pixels
+)
+
+// bunker is a shelter made of cells that crumble when hit
+type bunker struct {
+	r     *sdl.Renderer
+	x     int32
+	y     int32
+	cells [bunkerRows][bunkerCols]bool // Intact cells
+}
+
+// newBunker creates an intact bunker with an arch at the bottom
+func newBunker(r *sdl.Renderer, x, y int32) *bunker {
+	b := &bunker{r: r, x: x, y: y}
+
+	for row := range b.cells {
+		for col := range b.cells[row] {
+			b.cells[row][col] = true
+		}
+	}
+
+	// Round the top corners
+	b.cells[0][0], b.cells[0][1], b.cells[1][0] = false, false, false
+	b.cells[0][bunkerCols-1], b.cells[0][bunkerCols-2], b.cells[1][bunkerCols-1] = false, false, false
+
+	// Cut out the arch
+	for row := bunkerRows - 2; row < bunkerRows; row++ {
+		for col := 4; col < bunkerCols-4; col++ {
+			b.cells[row][col] = false
+		}
+	}
+
+	return b
+}
+
+// Draw draws the intact cells
+func (b *bunker) Draw() {
+	c := themeColor(roleBunker)
+	b.r.SetDrawColor(c.R, c.G, c.B, 0xFF)
+
+	for row := range b.cells {
+		for col := range b.cells[row] {
+			if b.cells[row][col] {
+				b.r.FillRect(&sdl.Rect{
+					X: b.x + int32(col)*bunkerCell,
+					Y: b.y + int32(row)*bunkerCell,
+					W: bunkerCell,
+					H: bunkerCell,
+				})
+			}
+		}
+	}
+}
+
+// box returns the bunker bounding box
+func (b *bunker) box() collision.Box {
+	return collision.Box{X: b.x, Y: b.y, W: bunkerCols * bunkerCell, H: bunkerRows * bunkerCell}
+}
+
+// hit destroys all intact cells a box overlaps and returns true if there were
+// any
+func (b *bunker) hit(box collision.Box) (hit bool) {
+	if !b.box().Intersects(box) {
+		return
+	}
+
+	// Cells are a regular grid, only the overlapped range needs testing
+	col1 := max(0, int((box.X-b.x)/bunkerCell))
+	col2 := min(bunkerCols-1, int((box.X+box.W-1-b.x)/bunkerCell))
+	row1 := max(0, int((box.Y-b.y)/bunkerCell))
+	row2 := min(bunkerRows-1, int((box.Y+box.H-1-b.y)/bunkerCell))
+	for row := row1; row <= row2; row++ {
+		for col := col1; col <= col2; col++ {
+			if b.cells[row][col] {
+				b.cells[row][col] = false
+				hit = true
+			}
+		}
+	}
+
+	return
+}
+
+// bunkerList holds all bunkers of a level
+type bunkerList []*bunker
+
+// newBunkers places n bunkers evenly spaced above the player
+func newBunkers(r *sdl.Renderer, n int) bunkerList {
+	maxX, maxY := screenSize(r)
+
+	bl := bunkerList{}
+	for i := 0; i < n; i++ {
+		x := int32(maxX*(i+1)/(n+1)) - bunkerCols*bunkerCell/2
+		bl = append(bl, newBunker(r, x, int32(maxY)-190))
+	}
+
+	return bl
+}
+
+// Draw draws all bunkers
+func (bl *bunkerList) Draw() {
+	for _, b := range *bl {
+		b.Draw()
+	}
+}
+
+// index rebuilds the broadphase of the bunkers, bunkers don't move so this is
+// only needed when the list is replaced
+func (bl *bunkerList) index(broad *collision.Grid) {
+	broad.Reset()
+	for _, b := range *bl {
+		broad.Insert(b.box())
+	}
+}
+
+// testHit removes bullets that have hit a bunker, broadphase IDs index the
+// list
+func (bl *bunkerList) testHit(broad *collision.Grid, bullets *bulletList) {
+	for i := 0; i < len(*bullets); i++ {
+		bt := (*bullets)[i]
+
+		hit := false
+		broad.Query(bt.box(), func(id int) bool {
+			hit = (*bl)[id].hit(bt.box())
+			return !hit
+		})
+		if hit {
+			// The removal shifts the next bullet into this slot
+			bullets.remove(bt)
+			i--
+		}
+	}
+}
+
+// erode destroys the cells aliens fly through
+func (bl *bunkerList) erode(broad *collision.Grid, aliens []*alien) {
+	for _, a := range aliens {
+		broad.Query(a.box(), func(id int) bool {
+			(*bl)[id].hit(a.box())
+			return true
+		})
+	}
+}
+
+// copyBunkers returns a copy of a bunker list
+func copyBunkers(bl bunkerList) bunkerList {
+	c := make(bunkerList, len(bl))
+	for i, b := range bl {
+		cb := *b
+		c[i] = &cb
+	}
+
+	return c
+}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// button is a screen area that can be clicked or tapped
+type button struct {
+	label string
+	rect  sdl.Rect
+	click func() // Nil for virtual buttons that are held
+}
+
+// hit returns true if a point is on the button
+func (b *button) hit(x, y int32) bool {
+	return x >= b.rect.X && x < b.rect.X+b.rect.W && y >= b.rect.Y && y < b.rect.Y+b.rect.H
+}
+
+// findButton returns the button at a point or nil
+func findButton(buttons []*button, x, y int32) *button {
+	for _, b := range buttons {
+		if b.hit(x, y) {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// drawButtons draws buttons as outlined labels
+func drawButtons(r *sdl.Renderer, font *ttf.Font, buttons []*button, c sdl.Color) {
+	r.SetDrawColor(c.R, c.G, c.B, 0xFF)
+	for _, b := range buttons {
+		r.DrawRect(&b.rect)
+		drawText(r, font, b.label, b.rect.X+b.rect.W/2, b.rect.Y+(b.rect.H-int32(font.Height()))/2, c)
+	}
+}
+
+// registerButtons makes buttons clickable with the left mouse button, clicks
+// that miss all buttons go to miss (optional)
+// The buttons are bound by pointer so scenes can change them.
+// Taps are clicks as well unless the scene handles touches itself.
+func (g *Game) registerButtons(buttons *[]*button, miss func(x, y int32, button uint8)) {
+	g.a.RegisterMouseCallback(func(x, y int32, mb uint8) {
+		if b := findButton(*buttons, x, y); b != nil && mb == sdl.BUTTON_LEFT {
+			b.click()
+			return
+		}
+		if miss != nil {
+			miss(x, y, mb)
+		}
+	})
+}
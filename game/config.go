@@ -0,0 +1,162 @@
+package game
+
+// configData is the serialized form of a game config
+// Fields added later are zero in older files, their zero value has to keep
+// the behavior from before they existed.
+type configData struct {
+	Aliens alienGridData `json:"aliens"`
+	Player playerData    `json:"player"`
+	Boss   bossData      `json:"boss"`
+	Levels []*level      `json:"levels,omitempty"`
+
+	// Difficulty is the preset the game was started with, the options set it
+	Difficulty string          `json:"difficulty,omitempty"`
+	Custom     *difficultyData `json:"custom,omitempty"` // Values of the custom difficulty
+	Assists    assists         `json:"assists"`
+}
+
+// alienGridData is the serialized form of an alien grid config
+type alienGridData struct {
+	Rows            int       `json:"rows"`
+	Cols            int       `json:"cols"`
+	MarginRow       int       `json:"marginRow"`
+	MarginCol       int       `json:"marginCol"`
+	ReturnPoint     int32     `json:"returnPoint"`
+	SpeedMax        int       `json:"speedMax"`
+	SpeedStep       int       `json:"speedStep"`
+	BulletSpeed     int32     `json:"bulletSpeed"`
+	FireRate        float64   `json:"fireRate"`
+	StepSizeX       int32     `json:"stepSizeX"`
+	StepSizeY       int32     `json:"stepSizeY"`
+	BoxHits         bool      `json:"boxHits"` // Test hits against bounding boxes instead of sprite masks
+	RowTypes        []string  `json:"rowTypes"`
+	MaxDivers       int       `json:"maxDivers"`
+	DiveRates       []float64 `json:"diveRates"`
+	DiveFireRate    float64   `json:"diveFireRate"`
+	DiveReturnTicks int       `json:"diveReturnTicks"`
+}
+
+// playerData is the serialized form of a player config
+type playerData struct {
+	StepSize          int32 `json:"stepSize"`
+	BulletSpeed       int32 `json:"bulletSpeed"`
+	Lifes             int   `json:"lifes"`
+	BoxHits           bool  `json:"boxHits"` // Test hits against bounding boxes instead of sprite masks
+	RespawnTicks      int   `json:"respawnTicks"`
+	InvulnerableTicks int   `json:"invulnerableTicks"`
+	ExtraLifes        []int `json:"extraLifes"`
+	ExtraLifeEvery    int   `json:"extraLifeEvery"`
+}
+
+// bossData is the serialized form of a boss config
+type bossData struct {
+	Every  int   `json:"every"`
+	HP     int   `json:"hp"`
+	HPStep int   `json:"hpStep"`
+	Speed  int32 `json:"speed"`
+	Points int   `json:"points"`
+}
+
+// data returns the serialized form of the config
+func (c *Config) data() *configData {
+	var custom *difficultyData
+	if c.custom != nil {
+		custom = c.custom.data()
+	}
+
+	return &configData{
+		Aliens: alienGridData{
+			Rows:            c.agc.rows,
+			Cols:            c.agc.cols,
+			MarginRow:       c.agc.marginRow,
+			MarginCol:       c.agc.marginCol,
+			ReturnPoint:     c.agc.returnPoint,
+			SpeedMax:        c.agc.speedMax,
+			SpeedStep:       c.agc.speedStep,
+			BulletSpeed:     c.agc.bulletSpeed,
+			FireRate:        c.agc.fireRate,
+			StepSizeX:       c.agc.stepSizeX,
+			StepSizeY:       c.agc.stepSizeY,
+			BoxHits:         !c.agc.pixelPerfect,
+			RowTypes:        c.agc.rowTypes,
+			MaxDivers:       c.agc.maxDivers,
+			DiveRates:       c.agc.diveRates,
+			DiveFireRate:    c.agc.diveFireRate,
+			DiveReturnTicks: c.agc.diveReturnTicks,
+		},
+		Player: playerData{
+			StepSize:    c.pc.stepSize,
+			BulletSpeed: c.pc.bulletSpeed,
+			Lifes:       c.pc.lifes,
+			BoxHits:     !c.pc.pixelPerfect,
+
+			RespawnTicks:      c.pc.respawnTicks,
+			InvulnerableTicks: c.pc.invulnerableTicks,
+			ExtraLifes:        c.pc.extraLifes,
+			ExtraLifeEvery:    c.pc.extraLifeEvery,
+		},
+		Boss: bossData{
+			Every:  c.bc.every,
+			HP:     c.bc.hp,
+			HPStep: c.bc.hpStep,
+			Speed:  c.bc.speed,
+			Points: c.bc.points,
+		},
+		Levels:     c.levels,
+		Difficulty: c.difficulty,
+		Custom:     custom,
+		Assists:    c.assists,
+	}
+}
+
+// config returns the config the data describes
+func (d *configData) config() *Config {
+	var custom *difficulty
+	if d.Custom != nil {
+		custom = d.Custom.custom()
+	}
+
+	return &Config{
+		agc: &alienGridConfig{
+			rows:            d.Aliens.Rows,
+			cols:            d.Aliens.Cols,
+			marginRow:       d.Aliens.MarginRow,
+			marginCol:       d.Aliens.MarginCol,
+			returnPoint:     d.Aliens.ReturnPoint,
+			speedMax:        d.Aliens.SpeedMax,
+			speedStep:       d.Aliens.SpeedStep,
+			bulletSpeed:     d.Aliens.BulletSpeed,
+			fireRate:        d.Aliens.FireRate,
+			stepSizeX:       d.Aliens.StepSizeX,
+			stepSizeY:       d.Aliens.StepSizeY,
+			pixelPerfect:    !d.Aliens.BoxHits,
+			rowTypes:        d.Aliens.RowTypes,
+			maxDivers:       d.Aliens.MaxDivers,
+			diveRates:       d.Aliens.DiveRates,
+			diveFireRate:    d.Aliens.DiveFireRate,
+			diveReturnTicks: d.Aliens.DiveReturnTicks,
+		},
+		pc: &playerConfig{
+			stepSize:     d.Player.StepSize,
+			bulletSpeed:  d.Player.BulletSpeed,
+			lifes:        d.Player.Lifes,
+			pixelPerfect: !d.Player.BoxHits,
+
+			respawnTicks:      d.Player.RespawnTicks,
+			invulnerableTicks: d.Player.InvulnerableTicks,
+			extraLifes:        d.Player.ExtraLifes,
+			extraLifeEvery:    d.Player.ExtraLifeEvery,
+		},
+		bc: &bossConfig{
+			every:  d.Boss.Every,
+			hp:     d.Boss.HP,
+			hpStep: d.Boss.HPStep,
+			speed:  d.Boss.Speed,
+			points: d.Boss.Points,
+		},
+		levels:     d.Levels,
+		difficulty: d.Difficulty,
+		custom:     custom,
+		assists:    d.Assists,
+	}
+}
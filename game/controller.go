@@ -0,0 +1,93 @@
+package game
+
+import (
+	"github.com/MichaelThessel/spacee/app"
+)
+
+// Rect is a rectangle in screen coordinates
+type Rect struct {
+	X, Y, W, H int32
+}
+
+// World is a read-only snapshot of the game state that is handed to
+// controllers on each tick
+type World struct {
+	Tick          int
+	Width         int32 // Viewport width
+	Height        int32 // Viewport height
+	Score         int
+	Level         int
+	Lifes         int
+	PlayerStep    int32 // How far the player moves per step
+	Player        Rect
+	Aliens        []Rect // Includes the boss during boss waves
+	PlayerBullets []Rect
+	AlienBullets  []Rect
+}
+
+// Action holds the player input for a single tick
+type Action struct {
+	Left  bool
+	Right bool
+	Fire  bool
+}
+
+// Controller decides on the player input for each tick
+type Controller interface {
+	Act(w *World) Action
+}
+
+// keyboard is a controller that turns key presses into actions
+type keyboard struct {
+	next Action
+}
+
+// newKeyboard returns a keyboard controller with the bound keys
+func newKeyboard(a *app.App, s *settings) *keyboard {
+	k := &keyboard{}
+
+	a.RegisterKeyCallback(s.key(actionLeft), func() { k.next.Left = true })   // left
+	a.RegisterKeyCallback(s.key(actionRight), func() { k.next.Right = true }) // right
+	a.RegisterKeyCallback(s.key(actionFire), func() { k.next.Fire = true })   // fire
+
+	return k
+}
+
+// Act returns the keys pressed since the last tick
+func (k *keyboard) Act(w *World) Action {
+	a := k.next
+	k.next = Action{}
+
+	return a
+}
+
+// world returns a snapshot of the current game state
+func (g *Game) world() *World {
+	maxX, maxY := screenSize(g.a.GetRenderer())
+
+	w := &World{
+		Tick:       g.ticks,
+		Width:      int32(maxX),
+		Height:     int32(maxY),
+		Score:      g.score,
+		Level:      g.level,
+		Lifes:      g.p.lifes,
+		PlayerStep: g.c.pc.stepSize,
+		Player:     Rect{X: g.p.x, Y: g.p.y, W: g.p.w, H: g.p.h},
+	}
+
+	for _, a := range g.ag.alienList {
+		w.Aliens = append(w.Aliens, Rect{X: a.x, Y: a.y, W: a.w, H: a.h})
+	}
+	if b := g.boss; b != nil {
+		w.Aliens = append(w.Aliens, Rect{X: b.x, Y: b.y, W: b.w, H: b.h})
+	}
+	for _, b := range *g.pbl {
+		w.PlayerBullets = append(w.PlayerBullets, Rect{X: b.x, Y: b.y, W: b.w, H: b.h})
+	}
+	for _, b := range *g.abl {
+		w.AlienBullets = append(w.AlienBullets, Rect{X: b.x, Y: b.y, W: b.w, H: b.h})
+	}
+
+	return w
+}
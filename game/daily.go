@@ -0,0 +1,271 @@
+package game
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MichaelThessel/spacee/app"
+)
+
+// dailyPrefix starts every daily challenge result
+const dailyPrefix = "lil' e invaders daily"
+
+// modifier changes the rules of a daily challenge
+type modifier struct {
+	name  string
+	apply func(c *Config)
+}
+
+// modifiers holds all daily challenge modifiers
+var modifiers = []modifier{
+	{"fast-grid", func(c *Config) { c.agc.speedMax += 2; c.agc.stepSizeX += 5 }},
+	{"double-fire", func(c *Config) { c.agc.fireRate *= 2 }},
+	{"fast-bullets", func(c *Config) { c.agc.bulletSpeed += c.agc.bulletSpeed / 2 }},
+	{"few-lifes", func(c *Config) { c.pc.lifes = 2 }},
+	{"divers", func(c *Config) {
+		c.agc.diveRates = []float64{0.01, 0.02, 0.03}
+		c.agc.maxDivers++
+	}},
+	{"slow-shots", func(c *Config) { c.pc.bulletSpeed /= 2 }},
+}
+
+// dailyChallenge holds the rules of a day's challenge
+type dailyChallenge struct {
+	date string // UTC date as YYYY-MM-DD
+	seed int64
+	mods []string
+}
+
+// dailyAttempt holds the scored attempt of a day
+type dailyAttempt struct {
+	Finished bool   `json:"finished"`
+	Score    int    `json:"score"`
+	Result   string `json:"result"`
+	Replay   string `json:"replay"`
+}
+
+// newDailyChallenge derives the challenge for a date (YYYY-MM-DD, empty:
+// today in UTC)
+func newDailyChallenge(date string) (*dailyChallenge, error) {
+	if date == "" {
+		date = time.Now().UTC().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid daily challenge date %s", date)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(dailyPrefix + " " + date))
+	dc := &dailyChallenge{date: date, seed: int64(h.Sum64() >> 1)}
+
+	// Two modifiers per day, picked with a generator of their own so the game
+	// starts out with the plain seed
+	rng := rand.New(rand.NewSource(dc.seed))
+	for _, i := range rng.Perm(len(modifiers))[:2] {
+		dc.mods = append(dc.mods, modifiers[i].name)
+	}
+	sort.Strings(dc.mods)
+
+	return dc, nil
+}
+
+// apply applies the modifiers to a config
+func (dc *dailyChallenge) apply(c *Config) {
+	for _, name := range dc.mods {
+		for _, m := range modifiers {
+			if m.name == name {
+				m.apply(c)
+			}
+		}
+	}
+}
+
+// result returns the result string to share for a score
+func (dc *dailyChallenge) result(score int) string {
+	return fmt.Sprintf("%s %s %s %d #%s", dailyPrefix, dc.date, strings.Join(dc.mods, ","), score, dc.checksum(score))
+}
+
+// checksum returns a checksum over the challenge and a score
+// It only catches typos, anyone can compute it for any score, only replays
+// prove a score.
+func (dc *dailyChallenge) checksum(score int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s|%d", dc.date, dc.seed, strings.Join(dc.mods, ","), score)))
+
+	return hex.EncodeToString(sum[:4])
+}
+
+// VerifyDaily checks that a shared daily challenge result is well formed and
+// matches the challenge of its date and returns the score and seed
+// The score itself can only be proven with the replay, see VerifyReplay.
+func VerifyDaily(res string) (score int, seed int64, err error) {
+	fields := strings.Fields(strings.TrimPrefix(res, dailyPrefix))
+	if !strings.HasPrefix(res, dailyPrefix) || len(fields) != 4 {
+		return 0, 0, fmt.Errorf("not a daily challenge result")
+	}
+
+	dc, err := newDailyChallenge(fields[0])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if fields[1] != strings.Join(dc.mods, ",") {
+		return 0, 0, fmt.Errorf("modifiers don't match the challenge of %s", dc.date)
+	}
+
+	score, err = strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid score %s", fields[2])
+	}
+
+	if fields[3] != "#"+dc.checksum(score) {
+		return 0, 0, fmt.Errorf("checksum mismatch")
+	}
+
+	return score, dc.seed, nil
+}
+
+// VerifyReplay replays a recorded game without rendering and returns the
+// score it reaches, the score has to match the one stored in the replay
+func VerifyReplay(a *app.App, path string) (int, error) {
+	rp, err := loadReplay(path)
+	if err != nil {
+		return 0, err
+	}
+	if rp.Mode != modeDaily || rp.Date == "" {
+		return 0, fmt.Errorf("only daily challenge replays can be verified, this is a %s replay", rp.Mode)
+	}
+
+	// Daily challenges play the default config without mods, difficulty or
+	// assists, so the challenge of the date rebuilds the config of the replay
+
+	g, err := New(a, &Options{
+		Seed:       rp.Seed,
+		Scene:      scenePlay,
+		Mode:       rp.Mode,
+		Date:       rp.Date,
+		Controller: &replayer{inputs: rp.Inputs},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if g.seed != rp.Seed {
+		return 0, fmt.Errorf("replay seed doesn't match the challenge")
+	}
+
+	over := false
+	g.onGameOver = func() {
+		over = true
+		a.ClearCallbacks()
+		a.Quit()
+	}
+
+	// A game has to end with its last input
+	a.RegisterUpdateCallback(func() {
+		if g.ticks > len(rp.Inputs) {
+			a.ClearCallbacks()
+			a.Quit()
+		}
+	})
+
+	if err := run(a); err != nil {
+		return g.score, fmt.Errorf("replay stopped: %v", err)
+	}
+
+	if !over {
+		return g.score, fmt.Errorf("replay ended without game over")
+	}
+
+	if g.score != rp.Score {
+		return g.score, fmt.Errorf("replay scores %d but claims %d", g.score, rp.Score)
+	}
+
+	return g.score, nil
+}
+
+// beginDaily sets up today's challenge and uses up the scored attempt of the
+// day if it is still available
+func (g *Game) beginDaily() error {
+	var err error
+	g.daily, err = newDailyChallenge("")
+	if err != nil {
+		return err
+	}
+
+	attempts, path, err := g.loadDailyAttempts()
+	if err != nil {
+		return err
+	}
+
+	// Later attempts of the day are practice runs
+	if _, ok := attempts[g.daily.date]; ok {
+		g.practice = true
+		return nil
+	}
+
+	attempts[g.daily.date] = &dailyAttempt{}
+
+	return saveDailyAttempts(path, attempts)
+}
+
+// finishDaily saves the result of the scored attempt along with the path of
+// its replay
+func (g *Game) finishDaily(replayPath string) error {
+	if g.practice {
+		return nil
+	}
+
+	attempts, path, err := g.loadDailyAttempts()
+	if err != nil {
+		return err
+	}
+
+	attempts[g.daily.date] = &dailyAttempt{
+		Finished: true,
+		Score:    g.score,
+		Result:   g.daily.result(g.score),
+		Replay:   replayPath,
+	}
+
+	return saveDailyAttempts(path, attempts)
+}
+
+// loadDailyAttempts loads the daily attempts by date
+func (g *Game) loadDailyAttempts() (map[string]*dailyAttempt, string, error) {
+	dir, err := dataDir(g.o)
+	if err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, "daily.json")
+
+	attempts := map[string]*dailyAttempt{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return attempts, path, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return attempts, path, json.Unmarshal(data, &attempts)
+}
+
+// saveDailyAttempts saves the daily attempts
+func saveDailyAttempts(path string, attempts map[string]*dailyAttempt) error {
+	data, err := json.MarshalIndent(attempts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
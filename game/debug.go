@@ -0,0 +1,315 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MichaelThessel/spacee/collision"
+	"github.com/veandco/go-sdl2/sdl"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// debugLines is the number of console output lines shown, enough for the help
+const debugLines = 8
+
+// debugCommands holds the console commands by name
+var debugCommands = map[string]struct {
+	help  string
+	cheat bool // Changes the game, it isn't scored anymore
+	run   func(g *Game, args []string) (string, error)
+}{
+	"god":      {help: "toggle god mode", cheat: true, run: (*Game).debugGod},
+	"skip":     {help: "skip the level", cheat: true, run: (*Game).debugSkip},
+	"ufo":      {help: "spawn the boss", cheat: true, run: (*Game).debugUFO},
+	"firerate": {help: "firerate X: set the alien fire rate", cheat: true, run: (*Game).debugFireRate},
+	"lifes":    {help: "lifes N: give N lifes", cheat: true, run: (*Game).debugLifes},
+	"pause":    {help: "toggle pause", run: (*Game).debugPause},
+	"step":     {help: "step N: advance N ticks while paused", run: (*Game).debugStep},
+}
+
+// debug holds the debug overlay & console state
+type debug struct {
+	r       *sdl.Renderer
+	font    *ttf.Font
+	overlay bool     // Show the overlay
+	console bool     // Console is open
+	input   string   // Command line
+	last    string   // Last command
+	lines   []string // Console output
+	paused  bool     // Game is paused by the pause command
+	steps   int      // Ticks to advance while paused
+}
+
+// open opens the font
+func (d *debug) open() error {
+	var err error
+	d.font, err = openFont(16)
+
+	return err
+}
+
+// Close closes the font
+func (d *debug) Close() {
+	closeFont(d.font)
+	d.font = nil
+}
+
+// advance returns true if the game should tick
+func (d *debug) advance() bool {
+	if !d.console && !d.paused {
+		return true
+	}
+	if d.steps > 0 {
+		d.steps--
+		return true
+	}
+
+	return false
+}
+
+// print adds a line to the console output
+func (d *debug) print(line string) {
+	d.lines = append(d.lines, line)
+	if len(d.lines) > debugLines {
+		d.lines = d.lines[len(d.lines)-debugLines:]
+	}
+}
+
+// toggleOverlay shows or hides the overlay
+func (g *Game) toggleOverlay() {
+	g.debug.overlay = !g.debug.overlay
+}
+
+// toggleConsole opens or closes the console, the game pauses while it is open
+func (g *Game) toggleConsole() {
+	d := g.debug
+	d.console = !d.console
+	d.input = ""
+
+	if !d.console {
+		g.a.StopTextInput()
+		return
+	}
+
+	g.a.StartTextInput(g.consoleKey, func(text string) {
+		d.input += strings.ReplaceAll(text, "`", "")
+	})
+}
+
+// consoleKey handles a key press while the console is open
+func (g *Game) consoleKey(key sdl.Keycode) {
+	d := g.debug
+
+	switch key {
+	case sdl.K_ESCAPE, sdl.K_BACKQUOTE:
+		g.toggleConsole()
+	case sdl.K_BACKSPACE:
+		if len(d.input) > 0 {
+			d.input = d.input[:len(d.input)-1]
+		}
+	case sdl.K_UP:
+		d.input = d.last
+	case sdl.K_RETURN:
+		if strings.TrimSpace(d.input) != "" {
+			d.last = d.input
+			g.runCommand(d.input)
+		}
+		d.input = ""
+	}
+}
+
+// runCommand runs a console command line
+func (g *Game) runCommand(line string) {
+	d := g.debug
+	d.print("> " + line)
+
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return
+	}
+	if fields[0] == "help" {
+		names := []string{}
+		for name := range debugCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d.print(fmt.Sprintf("%s - %s", name, debugCommands[name].help))
+		}
+		return
+	}
+
+	cmd, ok := debugCommands[fields[0]]
+	if !ok {
+		d.print(fmt.Sprintf("unknown command %s, try help", fields[0]))
+		return
+	}
+
+	out, err := cmd.run(g, fields[1:])
+	if err != nil {
+		d.print(err.Error())
+		return
+	}
+	if cmd.cheat && !g.cheated {
+		g.cheated = true
+		out += " (this game isn't scored anymore)"
+	}
+	if out != "" {
+		d.print(out)
+	}
+}
+
+// debugGod toggles god mode
+func (g *Game) debugGod(args []string) (string, error) {
+	g.p.god = !g.p.god
+
+	return fmt.Sprintf("god mode %v", g.p.god), nil
+}
+
+// debugSkip starts the next level
+func (g *Game) debugSkip(args []string) (string, error) {
+	if err := g.startLevel(g.a.GetRenderer()); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("level %d", g.level), nil
+}
+
+// debugUFO replaces the alien grid with a boss
+func (g *Game) debugUFO(args []string) (string, error) {
+	if g.boss != nil {
+		return "", fmt.Errorf("the boss is already there")
+	}
+
+	var err error
+	g.boss, err = newBoss(g.a.GetRenderer(), g.c.bc, g.c.agc.bulletSpeed, g.c.agc.pixelPerfect, g.rng, max(1, g.level/max(1, g.c.bc.every)))
+	if err != nil {
+		return "", err
+	}
+	*g.ag = alienGrid{
+		c:     g.ag.c,
+		r:     g.ag.r,
+		rng:   g.rng,
+		level: g.level,
+		broad: collision.NewGrid(128),
+		hooks: g.hooks,
+	}
+
+	return "boss spawned", nil
+}
+
+// debugFireRate sets the alien fire rate
+func (g *Game) debugFireRate(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: firerate X")
+	}
+
+	rate, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return "", fmt.Errorf("fire rate must be between 0 and 1")
+	}
+	g.ag.c.fireRate = rate
+
+	return fmt.Sprintf("fire rate %g", rate), nil
+}
+
+// debugLifes gives the player lifes
+func (g *Game) debugLifes(args []string) (string, error) {
+	n := 1
+	if len(args) == 1 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("usage: lifes N")
+		}
+	}
+	g.p.lifes = min(g.p.lifes+n, 99)
+
+	return fmt.Sprintf("%d lifes", g.p.lifes), nil
+}
+
+// debugPause toggles the pause
+func (g *Game) debugPause(args []string) (string, error) {
+	g.debug.paused = !g.debug.paused
+	g.debug.steps = 0
+
+	return fmt.Sprintf("paused %v", g.debug.paused), nil
+}
+
+// debugStep advances the paused game by some ticks
+func (g *Game) debugStep(args []string) (string, error) {
+	n := 1
+	if len(args) == 1 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("usage: step N")
+		}
+	}
+	g.debug.steps += n
+
+	return fmt.Sprintf("stepping %d ticks", n), nil
+}
+
+// drawDebug draws the overlay and the console
+func (g *Game) drawDebug() {
+	d := g.debug
+	maxX, maxY := screenSize(d.r)
+	c := themeColor(roleDebug)
+
+	if d.overlay {
+		// Hitboxes
+		d.r.SetDrawColor(c.R, c.G, c.B, 0xFF)
+		drawBox(d.r, g.p.box())
+		for _, a := range g.ag.alienList {
+			drawBox(d.r, a.box())
+		}
+		for _, b := range *g.pbl {
+			drawBox(d.r, b.box())
+		}
+		for _, b := range *g.abl {
+			drawBox(d.r, b.box())
+		}
+		if g.boss != nil {
+			drawBox(d.r, g.boss.box())
+		}
+
+		// Grid bounds & the lines the grid turns at
+		bc := themeColor(roleDebugBounds)
+		d.r.SetDrawColor(bc.R, bc.G, bc.B, 0xFF)
+		if len(g.ag.alienList) > 0 {
+			x1, y1, x2, y2 := g.ag.getDimensions()
+			d.r.DrawRect(&sdl.Rect{X: x1, Y: y1, W: x2 - x1, H: y2 - y1})
+		}
+		if g.ag.c != nil {
+			rp := int(g.ag.c.returnPoint)
+			d.r.DrawLine(rp, 0, rp, maxY)
+			d.r.DrawLine(maxX-rp, 0, maxX-rp, maxY)
+		}
+
+		fps, frameTime := g.a.GetFrameStats()
+		drawTextLeft(d.r, d.font, fmt.Sprintf(
+			"FPS %.0f  FRAME %.2fMS  TICK %d  ALIENS %d  PBL %d  ABL %d",
+			fps, float64(frameTime.Microseconds())/1000, g.ticks, len(g.ag.alienList), len(*g.pbl), len(*g.abl),
+		), 10, 60, c)
+	}
+
+	if d.console {
+		h := int32(debugLines+2) * 20
+		cc := themeColor(roleConsole)
+		d.r.SetDrawColor(cc.R, cc.G, cc.B, 0xFF)
+		d.r.FillRect(&sdl.Rect{X: 0, Y: int32(maxY) - h, W: int32(maxX), H: h})
+
+		for i, line := range d.lines {
+			drawTextLeft(d.r, d.font, line, 10, int32(maxY)-h+10+int32(i)*20, c)
+		}
+		drawTextLeft(d.r, d.font, "> "+d.input+"_", 10, int32(maxY)-30, c)
+	}
+}
+
+// drawBox outlines a box in the current draw color
+func drawBox(r *sdl.Renderer, b collision.Box) {
+	r.DrawRect(&sdl.Rect{X: b.X, Y: b.Y, W: b.W, H: b.H})
+}
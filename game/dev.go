@@ -0,0 +1,255 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// devAssetDir is the directory dev mode watches for changed assets
+const devAssetDir = "assets"
+
+// loadConfig loads a config file, missing values keep their defaults
+func loadConfig(path string) (*configData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load config: %v", err)
+	}
+
+	d := defaultConfig().data()
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("couldn't parse config %s: %v", path, err)
+	}
+
+	if err := d.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+
+	return d, nil
+}
+
+// validate checks values the game can't run with
+func (d *configData) validate() error {
+	switch {
+	case d.Aliens.Rows < 1 || d.Aliens.Cols < 1:
+		return fmt.Errorf("aliens need at least one row and column")
+	case d.Aliens.SpeedMax < 1 || d.Aliens.SpeedStep < 1:
+		return fmt.Errorf("alien speedMax and speedStep must be positive")
+	case d.Player.Lifes < 1:
+		return fmt.Errorf("the player needs at least one life")
+	}
+
+	if _, err := findDifficulty(d.Difficulty); err != nil {
+		return err
+	}
+	if d.Custom != nil {
+		if err := d.Custom.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range d.Aliens.RowTypes {
+		if _, ok := alienTypes[name]; !ok {
+			return fmt.Errorf("unknown alien type %s", name)
+		}
+	}
+
+	return nil
+}
+
+// devWatcher reloads the config file and assets of a running game when they
+// change
+type devWatcher struct {
+	g        *Game
+	modTimes map[string]time.Time // Modification times by path
+	ticks    int
+}
+
+// newDevWatcher returns a watcher for the files of the game
+func newDevWatcher(g *Game) *devWatcher {
+	w := &devWatcher{g: g}
+	w.modTimes = w.scan()
+
+	return w
+}
+
+// scan returns the modification times of all watched files
+func (w *devWatcher) scan() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+
+	if w.g.o.Config != "" {
+		if info, err := os.Stat(w.g.o.Config); err == nil {
+			modTimes[filepath.Clean(w.g.o.Config)] = info.ModTime()
+		}
+	}
+
+	filepath.WalkDir(devAssetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+		return nil
+	})
+
+	return modTimes
+}
+
+// update checks for changed files about once a second
+func (w *devWatcher) update() {
+	w.ticks++
+	if w.ticks%max(1, int(w.g.a.GetFrameRate())) != 0 {
+		return
+	}
+
+	modTimes := w.scan()
+	changed := []string{}
+	for path, t := range modTimes {
+		if !t.Equal(w.modTimes[path]) {
+			changed = append(changed, path)
+		}
+	}
+	w.modTimes = modTimes
+	sort.Strings(changed)
+
+	for _, path := range changed {
+		if err := w.reload(path); err != nil {
+			slog.Error("couldn't reload", "path", path, "err", err)
+		}
+	}
+}
+
+// reload applies a changed file to the running game
+func (w *devWatcher) reload(path string) error {
+	g := w.g
+
+	switch {
+	case path == filepath.Clean(g.o.Config):
+		return g.reloadConfig()
+
+	case filepath.Ext(path) == ".lua":
+		// Scripts reload themselves
+		return nil
+
+	case path == filepath.Join(devAssetDir, "dives.json"):
+		paths, err := loadDivePaths(path)
+		if err != nil {
+			return err
+		}
+		g.ag.paths = paths
+		g.unscore("dive paths changed")
+		slog.Info("reloaded dive paths", "paths", len(paths))
+		return nil
+
+	case filepath.Clean(filepath.Dir(path)) == filepath.Clean(g.o.Levels):
+		levels, err := loadLevels(g.o.Levels)
+		if err != nil {
+			return err
+		}
+		g.levels = levels
+		g.c.levels = levels
+		g.unscore("levels changed")
+		slog.Info("reloaded levels, changes apply from the next level", "levels", len(levels))
+		return nil
+	}
+
+	r := g.a.GetRenderer()
+	loaded, err := assetsFor(r).reload(r, path)
+	if err != nil {
+		return err
+	}
+	if loaded {
+		slog.Info("reloaded", "path", path)
+	} else {
+		slog.Info("changed file isn't loaded yet or needs a restart", "path", path)
+	}
+
+	return nil
+}
+
+// reloadConfig loads the config file and applies it to the running game
+func (g *Game) reloadConfig() error {
+	d, err := loadConfig(g.o.Config)
+	if err != nil {
+		return err
+	}
+
+	// Daily challenges keep their fixed rules
+	if g.mode.daily {
+		slog.Info("config changed, daily challenges ignore it")
+		return nil
+	}
+
+	changes := configChanges(g.fileConfig, d)
+	for _, change := range changes {
+		slog.Info("config changed", "value", change)
+	}
+	g.fileConfig = d
+
+	// The replay only keeps the latest config
+	if len(changes) > 0 {
+		g.unscore("config changed")
+	}
+
+	levels, difficulty, custom, assists := g.c.levels, g.c.difficulty, g.c.custom, g.c.assists
+	g.c = d.config()
+	g.c.levels, g.c.difficulty, g.c.custom, g.c.assists = levels, difficulty, custom, assists
+	g.c.preset().applyPlayer(g.c.pc)
+
+	// The running game picks up the new values, the grid keeps its layout
+	if g.p != nil {
+		g.p.c = g.c.pc
+	}
+	if g.boss != nil {
+		g.boss.c = g.c.bc
+	}
+	if g.ag.c != nil {
+		agc := g.levelConfig(g.c.level(g.level))
+		agc.rows, agc.cols, agc.layout = g.ag.c.rows, g.ag.c.cols, g.ag.c.layout
+		*g.ag.c = *agc
+	}
+
+	return nil
+}
+
+// configChanges lists the values that differ between two configs
+func configChanges(a, b *configData) []string {
+	fa, fb := map[string]string{}, map[string]string{}
+	flattenConfig("", reflect.ValueOf(*a), fa)
+	flattenConfig("", reflect.ValueOf(*b), fb)
+
+	changes := []string{}
+	for key, vb := range fb {
+		if va := fa[key]; va != vb {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, va, vb))
+		}
+	}
+	sort.Strings(changes)
+
+	return changes
+}
+
+// flattenConfig collects the values of a config struct by JSON path
+func flattenConfig(prefix string, v reflect.Value, values map[string]string) {
+	if v.Kind() != reflect.Struct {
+		data, _ := json.Marshal(v.Interface())
+		values[prefix] = string(data)
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		flattenConfig(name, v.Field(i), values)
+	}
+}
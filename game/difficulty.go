@@ -0,0 +1,117 @@
+package game
+
+import (
+	"fmt"
+)
+
+const (
+	// difficultyNormal is the difficulty of the unchanged config
+	difficultyNormal = "normal"
+
+	// difficultyCustom is the difficulty with values picked by the player
+	difficultyCustom = "custom"
+)
+
+// difficulty is a preset that scales the alien grid & player config
+type difficulty struct {
+	name        string
+	title       string
+	fireRate    float64 // Alien fire rate factor
+	bulletSpeed float64 // Alien bullet speed factor
+	speedMax    int     // Added to the grid max speed, higher starts slower
+	stepSizeY   float64 // Factor of how far the grid drops
+	lifes       int     // Added to the player lifes
+}
+
+// difficulties holds the presets from easy to hard, the values of the custom
+// preset come with the config
+var difficulties = []*difficulty{
+	{name: "easy", title: "EASY", fireRate: 0.6, bulletSpeed: 0.75, speedMax: 1, stepSizeY: 0.7, lifes: 2},
+	{name: difficultyNormal, title: "NORMAL", fireRate: 1, bulletSpeed: 1, stepSizeY: 1},
+	{name: "hard", title: "HARD", fireRate: 1.5, bulletSpeed: 1.3, speedMax: -1, stepSizeY: 1.3, lifes: -1},
+	{name: difficultyCustom, title: "CUSTOM", fireRate: 1, bulletSpeed: 1, stepSizeY: 1},
+}
+
+// difficultyData is the serialized form of the custom difficulty
+type difficultyData struct {
+	FireRate    float64 `json:"fireRate"`
+	BulletSpeed float64 `json:"bulletSpeed"`
+	SpeedMax    int     `json:"speedMax"`
+	StepSizeY   float64 `json:"stepSizeY"`
+	Lifes       int     `json:"lifes"`
+}
+
+// findDifficulty returns the preset of a name, empty names are normal
+func findDifficulty(name string) (*difficulty, error) {
+	if name == "" {
+		name = difficultyNormal
+	}
+
+	for _, d := range difficulties {
+		if d.name == name {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown difficulty %s", name)
+}
+
+// custom returns the custom difficulty the data describes
+func (d *difficultyData) custom() *difficulty {
+	return &difficulty{
+		name:        difficultyCustom,
+		title:       "CUSTOM",
+		fireRate:    d.FireRate,
+		bulletSpeed: d.BulletSpeed,
+		speedMax:    d.SpeedMax,
+		stepSizeY:   d.StepSizeY,
+		lifes:       d.Lifes,
+	}
+}
+
+// data returns the serialized form of the difficulty
+func (d *difficulty) data() *difficultyData {
+	return &difficultyData{
+		FireRate:    d.fireRate,
+		BulletSpeed: d.bulletSpeed,
+		SpeedMax:    d.speedMax,
+		StepSizeY:   d.stepSizeY,
+		Lifes:       d.lifes,
+	}
+}
+
+// validate checks custom values the game can't run with
+func (d *difficultyData) validate() error {
+	if d.FireRate <= 0 || d.BulletSpeed <= 0 || d.StepSizeY <= 0 {
+		return fmt.Errorf("custom difficulty factors must be positive")
+	}
+
+	return nil
+}
+
+// preset returns the difficulty of the config
+func (c *Config) preset() *difficulty {
+	if c.difficulty == difficultyCustom && c.custom != nil {
+		return c.custom
+	}
+
+	d, err := findDifficulty(c.difficulty)
+	if err != nil {
+		d, _ = findDifficulty(difficultyNormal)
+	}
+
+	return d
+}
+
+// applyGrid scales an alien grid config
+func (d *difficulty) applyGrid(agc *alienGridConfig) {
+	agc.fireRate *= d.fireRate
+	agc.bulletSpeed = max(1, int32(float64(agc.bulletSpeed)*d.bulletSpeed))
+	agc.speedMax = max(1, agc.speedMax+d.speedMax)
+	agc.stepSizeY = max(1, int32(float64(agc.stepSizeY)*d.stepSizeY))
+}
+
+// applyPlayer scales a player config
+func (d *difficulty) applyPlayer(pc *playerConfig) {
+	pc.lifes = max(1, pc.lifes+d.lifes)
+}
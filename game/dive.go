@@ -0,0 +1,192 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// point is a position with sub pixel precision
+type point struct {
+	x, y float64
+}
+
+// divePath describes a dive as a chain of cubic Bezier curves
+// Points are offsets from the dive start in fractions of the viewport size
+// and are mirrored horizontally for aliens right of the center so dives lead
+// towards the middle. The point at index Aim is replaced with the player
+// position when the dive starts.
+type divePath struct {
+	Name   string       `json:"name"`
+	Ticks  int          `json:"ticks"`  // Duration of the dive
+	Wrap   bool         `json:"wrap"`   // Re-enter from the top instead of flying back
+	Aim    int          `json:"aim"`    // Index of the point that targets the player (-1: none)
+	Points [][2]float64 `json:"points"` // Start point and 3 points per curve
+}
+
+// loadDivePaths loads dive paths from a JSON file
+func loadDivePaths(path string) ([]*divePath, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load dive paths: %v", err)
+	}
+
+	var paths []*divePath
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, fmt.Errorf("couldn't parse dive paths: %v", err)
+	}
+
+	for _, p := range paths {
+		if len(p.Points) < 4 || (len(p.Points)-1)%3 != 0 {
+			return nil, fmt.Errorf("dive path %s needs 1 + 3n points", p.Name)
+		}
+		if p.Aim >= len(p.Points) {
+			return nil, fmt.Errorf("dive path %s aims at a missing point", p.Name)
+		}
+		if p.Ticks <= 0 {
+			return nil, fmt.Errorf("dive path %s needs a duration", p.Name)
+		}
+	}
+
+	return paths, nil
+}
+
+// dive holds the state of an alien that has left the formation
+type dive struct {
+	points    []point // Absolute curve points
+	ticks     int     // Duration of the dive
+	wrap      bool
+	tick      int
+	returning bool  // Flying back to the formation
+	from      point // Where the return started
+}
+
+// newDive places a dive path for an alien diving at the player
+func newDive(p *divePath, a *alien, target point, maxX, maxY int) *dive {
+	mirror := 1.0
+	if int(a.x+a.w/2) > maxX/2 {
+		mirror = -1
+	}
+
+	d := &dive{ticks: p.Ticks, wrap: p.Wrap}
+	for i, rp := range p.Points {
+		pt := point{
+			x: float64(a.x) + rp[0]*mirror*float64(maxX),
+			y: float64(a.y) + rp[1]*float64(maxY),
+		}
+		if i == p.Aim {
+			pt = target
+		}
+		d.points = append(d.points, pt)
+	}
+
+	return d
+}
+
+// position returns the position on the path at t in [0, 1]
+func (d *dive) position(t float64) point {
+	curves := (len(d.points) - 1) / 3
+
+	c := int(t * float64(curves))
+	if c >= curves {
+		c = curves - 1
+	}
+	t = t*float64(curves) - float64(c)
+
+	p0, p1, p2, p3 := d.points[c*3], d.points[c*3+1], d.points[c*3+2], d.points[c*3+3]
+	u := 1 - t
+
+	return point{
+		x: u*u*u*p0.x + 3*u*u*t*p1.x + 3*u*t*t*p2.x + t*t*t*p3.x,
+		y: u*u*u*p0.y + 3*u*u*t*p1.y + 3*u*t*t*p2.y + t*t*t*p3.y,
+	}
+}
+
+// startDive sends a random alien of the formation on a dive
+func (ag *alienGrid) startDive(target point) {
+	if len(ag.paths) == 0 || ag.divers >= ag.c.maxDivers {
+		return
+	}
+
+	if ag.rng.Float64() > ag.diveRate() {
+		return
+	}
+
+	candidates := []*alien{}
+	for _, a := range ag.alienList {
+		if a.dive == nil {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	a := candidates[ag.rng.Intn(len(candidates))]
+	p := ag.paths[ag.rng.Intn(len(ag.paths))]
+	maxX, maxY := screenSize(ag.r)
+
+	// The target is where the alien's top left corner centers it on the player
+	target.x -= float64(a.w / 2)
+	target.y -= float64(a.h / 2)
+
+	a.dive = newDive(p, a, target, maxX, maxY)
+	ag.divers++
+}
+
+// diveRate returns the chance per tick that an alien starts a dive in the
+// current level
+func (ag *alienGrid) diveRate() float64 {
+	if len(ag.c.diveRates) == 0 {
+		return 0
+	}
+
+	i := ag.level - 1
+	if i >= len(ag.c.diveRates) {
+		i = len(ag.c.diveRates) - 1
+	}
+
+	return ag.c.diveRates[i]
+}
+
+// updateDivers moves all diving aliens and lets them fire
+func (ag *alienGrid) updateDivers(bullets *bulletList) {
+	for _, a := range ag.alienList {
+		if a.dive == nil {
+			continue
+		}
+		d := a.dive
+		d.tick++
+
+		if !d.returning {
+			pos := d.position(float64(d.tick) / float64(d.ticks))
+			a.x, a.y = int32(math.Round(pos.x)), int32(math.Round(pos.y))
+
+			if ag.rng.Float64() < ag.c.diveFireRate {
+				ag.fireFrom(a, bullets)
+			}
+
+			if d.tick >= d.ticks {
+				d.returning = true
+				d.tick = 0
+				if d.wrap {
+					a.y = -a.h
+				}
+				d.from = point{x: float64(a.x), y: float64(a.y)}
+			}
+			continue
+		}
+
+		// Fly back to the slot, which keeps moving with the formation
+		t := float64(d.tick) / float64(ag.c.diveReturnTicks)
+		if t >= 1 {
+			a.x, a.y = a.homeX, a.homeY
+			a.dive = nil
+			ag.divers--
+			continue
+		}
+		a.x = int32(math.Round(d.from.x + (float64(a.homeX)-d.from.x)*t))
+		a.y = int32(math.Round(d.from.y + (float64(a.homeY)-d.from.y)*t))
+	}
+}
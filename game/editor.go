@@ -0,0 +1,243 @@
+package game
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MichaelThessel/spacee/app"
+	"github.com/veandco/go-sdl2/sdl"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+const (
+	// Editor grid limits
+	editorMaxRows = 6
+	editorMaxCols = 11
+)
+
+// editor holds the level editor state
+type editor struct {
+	r       *sdl.Renderer
+	font    *ttf.Font
+	s       *sprite
+	l       *level
+	path    string // Where the level is saved
+	brush   int    // Index of the alien type placed on click
+	message string // Result of the last save
+}
+
+// newEditor creates an editor for the level file at path, a missing file
+// starts a new level from the config
+func newEditor(r *sdl.Renderer, c *Config, path string) (*editor, error) {
+	e := &editor{r: r, path: path}
+
+	var err error
+	if _, serr := os.Stat(path); serr == nil {
+		e.l, err = loadLevel(path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		e.l = newLevel(c.agc, 4)
+	}
+
+	e.s, err = loadSprite(r, "assets/alien.png")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create alien texture: %v", err)
+	}
+
+	return e, nil
+}
+
+// open opens the font
+func (e *editor) open() error {
+	var err error
+	e.font, err = openFont(20)
+
+	return err
+}
+
+// Close closes the font
+func (e *editor) Close() {
+	closeFont(e.font)
+	e.font = nil
+}
+
+// slot returns the screen rectangle of a grid slot, laid out like
+// newAlienGrid does
+func (e *editor) slot(row, col int) *sdl.Rect {
+	maxX, _ := screenSize(e.r)
+	_, cols := e.l.size()
+	startX := (int32(maxX) - 100*int32(cols) - 20) / 2
+
+	return &sdl.Rect{X: startX + 100*int32(col), Y: 50 + 106*int32(row), W: 80, H: 86}
+}
+
+// Draw draws the level and the editor help
+func (e *editor) Draw() {
+	maxX, maxY := screenSize(e.r)
+	c := themeColor(roleHUDText)
+	names := alienTypeNames()
+
+	// Slots
+	for row, line := range e.l.Grid {
+		for col := 0; col < len(line); col++ {
+			rect := e.slot(row, col)
+			typ, ok := alienTypes[symbolName(line[col])]
+			if !ok {
+				grid := themeColor(roleEditorGrid)
+				e.r.SetDrawColor(grid.R, grid.G, grid.B, 0xFF)
+				e.r.DrawRect(rect)
+				continue
+			}
+
+			t := themeColor(typ.color)
+			e.s.t.SetColorMod(t.R, t.G, t.B)
+			e.r.Copy(e.s.t, nil, rect)
+			e.s.t.SetColorMod(0xFF, 0xFF, 0xFF)
+		}
+	}
+
+	// Bunkers
+	bl := newBunkers(e.r, e.l.Bunkers)
+	bl.Draw()
+
+	rows, cols := e.l.size()
+	status := fmt.Sprintf(
+		"BRUSH: %s  ROWS: %d  COLS: %d  BUNKERS: %d  %s",
+		strings.ToUpper(names[e.brush]), rows, cols, e.l.Bunkers, e.message,
+	)
+	drawText(e.r, e.font, status, int32(maxX)/2, int32(maxY)-90, c)
+	drawText(e.r, e.font, "LEFT CLICK PLACE  RIGHT CLICK REMOVE  1-4 BRUSH  ARROWS SIZE  +/- BUNKERS", int32(maxX)/2, int32(maxY)-60, c)
+	drawText(e.r, e.font, "ENTER TEST  S SAVE  ESC MENU", int32(maxX)/2, int32(maxY)-30, c)
+}
+
+// click places or removes an alien at a screen position
+func (e *editor) click(x, y int32, button uint8) {
+	for row, line := range e.l.Grid {
+		for col := 0; col < len(line); col++ {
+			rect := e.slot(row, col)
+			if x < rect.X || x >= rect.X+rect.W || y < rect.Y || y >= rect.Y+rect.H {
+				continue
+			}
+
+			switch button {
+			case sdl.BUTTON_LEFT:
+				e.l.set(row, col, alienTypes[alienTypeNames()[e.brush]].symbol)
+			case sdl.BUTTON_RIGHT:
+				e.l.set(row, col, levelEmpty)
+			}
+			e.message = ""
+			return
+		}
+	}
+}
+
+// selectBrush selects the alien type placed on click
+func (e *editor) selectBrush(i int) {
+	if i < len(alienTypes) {
+		e.brush = i
+	}
+}
+
+// resize changes the grid size by rows & cols
+func (e *editor) resize(rows, cols int) {
+	r, c := e.l.size()
+	e.l.resize(max(1, min(r+rows, editorMaxRows)), max(1, min(c+cols, editorMaxCols)))
+	e.message = ""
+}
+
+// changeBunkers changes the number of bunkers by n
+func (e *editor) changeBunkers(n int) {
+	e.l.Bunkers = max(0, min(e.l.Bunkers+n, 6))
+	e.message = ""
+}
+
+// save saves the level to its file
+func (e *editor) save() {
+	if err := e.l.save(e.path); err != nil {
+		e.message = "SAVE FAILED"
+		slog.Error("couldn't save level", "path", e.path, "err", err)
+		return
+	}
+
+	e.message = "SAVED"
+}
+
+// symbolName returns the alien type name of a level symbol
+func symbolName(sym byte) string {
+	name, _ := alienTypeBySymbol(sym)
+
+	return name
+}
+
+// editorPath returns the level file the editor works on
+func (g *Game) editorPath() (string, error) {
+	if g.o.Level != "" {
+		return g.o.Level, nil
+	}
+
+	dir, err := dataDir(g.o)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "levels", "custom.json"), nil
+}
+
+// sceneEditor sets up the level editor, the level survives test plays but
+// the font is closed with the scene
+func (g *Game) sceneEditor() error {
+	g.testing = false
+
+	if g.editor == nil {
+		path, err := g.editorPath()
+		if err != nil {
+			return err
+		}
+
+		g.editor, err = newEditor(g.a.GetRenderer(), defaultConfig(), path)
+		if err != nil {
+			return err
+		}
+	}
+	e := g.editor
+	if err := e.open(); err != nil {
+		return err
+	}
+	g.a.Own(app.SceneScope, e.Close)
+
+	// Draw editor
+	g.a.RegisterRenderCallback(1, e.Draw)
+
+	g.a.RegisterMouseCallback(e.click)
+	for i, key := range []sdl.Keycode{sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4} {
+		i := i
+		g.a.RegisterKeyCallback(key, func() { e.selectBrush(i) }) // brush
+	}
+	g.a.RegisterKeyCallback(sdl.K_UP, func() { e.resize(-1, 0) })               // fewer rows
+	g.a.RegisterKeyCallback(sdl.K_DOWN, func() { e.resize(1, 0) })              // more rows
+	g.a.RegisterKeyCallback(sdl.K_LEFT, func() { e.resize(0, -1) })             // fewer columns
+	g.a.RegisterKeyCallback(sdl.K_RIGHT, func() { e.resize(0, 1) })             // more columns
+	g.a.RegisterKeyCallback(sdl.K_PLUS, func() { e.changeBunkers(1) })          // more bunkers
+	g.a.RegisterKeyCallback(sdl.K_EQUALS, func() { e.changeBunkers(1) })        // more bunkers
+	g.a.RegisterKeyCallback(sdl.K_MINUS, func() { e.changeBunkers(-1) })        // fewer bunkers
+	g.a.RegisterKeyCallback(sdl.K_s, e.save)                                    // save
+	g.a.RegisterKeyCallback(sdl.K_ESCAPE, func() { g.changeScene(sceneStart) }) // menu
+	g.a.RegisterKeyCallback(sdl.K_RETURN, func() {                              // test
+		if err := e.l.validate(); err != nil {
+			e.message = strings.ToUpper(err.Error())
+			return
+		}
+
+		g.testing = true
+		g.mode, _ = findMode(modeClassic)
+		g.seed = g.rng.Int63()
+		g.changeScene(scenePlay)
+	})
+
+	return nil
+}
@@ -7,99 +7,92 @@ import (
 	ttf "github.com/veandco/go-sdl2/sdl_ttf"
 )
 
+// result holds the results of a game shown on the end screen
+type result struct {
+	mode    string      // Mode title
+	score   int         // Game score
+	summary string      // Mode specific results
+	scores  []highScore // High score table of the mode
+	rank    int         // Rank of the score in the table (-1: not placed)
+	share   string      // Result to share (optional)
+	replay  bool        // A replay of the game has been saved
+}
+
 // end holds the end screen state
 type end struct {
 	r         *sdl.Renderer
 	scoreFont *ttf.Font
 	infoFont  *ttf.Font
-	score     int
+	res       *result
+	buttons   []*button
 }
 
 // newEnd returns a new end screen
-func newEnd(r *sdl.Renderer, score int) (*end, error) {
+func newEnd(r *sdl.Renderer, res *result) (*end, error) {
 	e := &end{
-		r:     r,
-		score: score,
+		r:   r,
+		res: res,
 	}
 
 	var err error
 
 	// Set score font
-	e.scoreFont, err = ttf.OpenFont("assets/font.ttf", 80)
+	e.scoreFont, err = openFont(80)
 	if err != nil {
-		return nil, fmt.Errorf("could not load font: %v", err)
+		return nil, err
 	}
 
 	// Set info font
-	e.infoFont, err = ttf.OpenFont("assets/font.ttf", 20)
+	e.infoFont, err = openFont(20)
 	if err != nil {
-		return nil, fmt.Errorf("could not load font: %v", err)
+		e.Close()
+		return nil, err
 	}
 
 	return e, nil
 }
 
+// Close closes the fonts
+func (e *end) Close() {
+	closeFont(e.scoreFont)
+	closeFont(e.infoFont)
+}
+
 // Draw draws the end screen
 func (e *end) Draw() {
-	maxX, maxY, _ := e.r.GetRendererOutputSize()
-
-	score, _ := e.scoreFont.RenderUTF8_Solid(
-		fmt.Sprintf("POINTS: %d", e.score),
-		sdl.Color{R: 0xF6, G: 0x25, B: 0x9B, A: 0},
-	)
-	defer score.Free()
-
-	info1, _ := e.scoreFont.RenderUTF8_Solid(
-		"GAME OVER",
-		sdl.Color{R: 0xF6, G: 0x25, B: 0x9B, A: 0},
-	)
-	defer info1.Free()
-
-	info2, _ := e.infoFont.RenderUTF8_Solid(
-		"PRESS ENTER TO RESTART",
-		sdl.Color{R: 0xF6, G: 0x25, B: 0x9B, A: 0},
-	)
-	defer info2.Free()
-
-	var clipRect sdl.Rect
-	score.GetClipRect(&clipRect)
-	info1.GetClipRect(&clipRect)
-	info2.GetClipRect(&clipRect)
-
-	scoreTex, _ := e.r.CreateTextureFromSurface(score)
-	info1Tex, _ := e.r.CreateTextureFromSurface(info1)
-	info2Tex, _ := e.r.CreateTextureFromSurface(info2)
-
-	e.r.Copy(
-		scoreTex,
-		nil,
-		&sdl.Rect{
-			X: int32(maxX)/2 - score.W/2,
-			Y: int32(maxY) / 2,
-			W: score.W,
-			H: score.H,
-		},
-	)
-
-	e.r.Copy(
-		info1Tex,
-		nil,
-		&sdl.Rect{
-			X: int32(maxX)/2 - info1.W/2,
-			Y: int32(maxY)/2 - 100,
-			W: info1.W,
-			H: info1.H,
-		},
-	)
+	maxX, maxY := screenSize(e.r)
+	x := int32(maxX) / 2
+	c := themeColor(roleHUDText)
+
+	drawText(e.r, e.scoreFont, "GAME OVER", x, 60, c)
+	drawText(e.r, e.scoreFont, fmt.Sprintf("POINTS: %d", e.res.score), x, 160, c)
+	drawText(e.r, e.infoFont, fmt.Sprintf("%s  %s", e.res.mode, e.res.summary), x, 270, c)
+	drawText(e.r, e.infoFont, e.res.share, x, 300, c)
+
+	// High score table, the new entry is marked
+	if len(e.res.scores) > 0 {
+		drawText(e.r, e.infoFont, "HIGH SCORES", x, 330, c)
+	}
+	for i, hs := range e.res.scores {
+		if i == 5 {
+			break
+		}
+
+		marker := " "
+		if i == e.res.rank {
+			marker = ">"
+		}
+		line := fmt.Sprintf("%s %d. %08d  %s", marker, i+1, hs.Score, hs.Summary)
+		if tags := hs.tags(); tags != "" {
+			line += "  " + tags
+		}
+		drawText(e.r, e.infoFont, line, x, 370+int32(i)*32, c)
+	}
 
-	e.r.Copy(
-		info2Tex,
-		nil,
-		&sdl.Rect{
-			X: int32(maxX)/2 - info2.W/2,
-			Y: int32(maxY)/2 + 100,
-			W: info2.W,
-			H: info2.H,
-		},
-	)
+	help := "PRESS ENTER TO RESTART OR ESC FOR THE MENU"
+	if e.res.replay {
+		help = "PRESS ENTER TO RESTART, R TO WATCH THE REPLAY OR ESC FOR THE MENU"
+	}
+	drawText(e.r, e.infoFont, help, x, int32(maxY)-120, c)
+	drawButtons(e.r, e.infoFont, e.buttons, c)
 }
@@ -0,0 +1,277 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/MichaelThessel/spacee/app"
+)
+
+const (
+	// Observation types
+	ObserveVector = "vector"
+	ObserveFrame  = "frame"
+
+	// envBullets is the number of alien bullets in a vector observation
+	envBullets = 8
+
+	// envSeed replaces seed 0 on reset, games always need a fixed seed to be
+	// reproducible
+	envSeed = 0x5eed
+)
+
+// envActions maps discrete action indices to actions
+var envActions = []Action{
+	{},                        // 0: noop
+	{Left: true},              // 1: left
+	{Right: true},             // 2: right
+	{Fire: true},              // 3: fire
+	{Left: true, Fire: true},  // 4: left & fire
+	{Right: true, Fire: true}, // 5: right & fire
+}
+
+// EnvConfig holds the environment configuration
+type EnvConfig struct {
+	Observation  string  // Observation type (ObserveVector or ObserveFrame)
+	FrameSkip    int     // Number of ticks an action is repeated for
+	FrameWidth   int     // Width of frame observations
+	FrameHeight  int     // Height of frame observations
+	DeathPenalty float64 // Reward subtracted for each life lost
+}
+
+// Observation holds what the agent gets to see after each step
+type Observation struct {
+	// Vector holds values normalized to [0, 1] (-1: absent):
+	// player x, lifes, player bullet x & y, envBullets alien bullets x & y
+	// closest to the ground first, alive, x & y for each alien grid slot and
+	// boss health, x & y (0, -1, -1 outside of boss waves)
+	// The grid slots cover the largest grid of the config & the level files,
+	// waves generated by scripts beyond that size are cut off.
+	Vector []float64 `json:"vector,omitempty"`
+
+	// Frame holds a downsampled grayscale frame, row by row
+	Frame  []uint8 `json:"frame,omitempty"`
+	Width  int     `json:"width,omitempty"`
+	Height int     `json:"height,omitempty"`
+}
+
+// Info holds diagnostic information about a step
+type Info struct {
+	Score int `json:"score"`
+	Level int `json:"level"`
+	Lifes int `json:"lifes"`
+	Ticks int `json:"ticks"`
+}
+
+// Env is a gym style environment for training agents
+type Env struct {
+	a     *app.App
+	c     *EnvConfig
+	g     *Game
+	agent *envAgent
+	done  bool
+	rows  int // Alien grid size of vector observations
+	cols  int
+}
+
+// envAgent is the controller that replays the action of the current step
+type envAgent struct {
+	act Action
+}
+
+// Act implements Controller
+func (ea *envAgent) Act(w *World) Action {
+	return ea.act
+}
+
+// NewEnv returns a new environment
+func NewEnv(a *app.App, c *EnvConfig) (*Env, error) {
+	switch c.Observation {
+	case ObserveVector, ObserveFrame:
+	default:
+		return nil, fmt.Errorf("invalid observation type %s", c.Observation)
+	}
+
+	if c.FrameSkip < 1 {
+		c.FrameSkip = 1
+	}
+
+	return &Env{a: a, c: c, agent: &envAgent{}}, nil
+}
+
+// ActionCount returns the number of discrete actions
+func (e *Env) ActionCount() int {
+	return len(envActions)
+}
+
+// Action returns the action for a discrete action index
+func (e *Env) Action(i int) (Action, error) {
+	if i < 0 || i >= len(envActions) {
+		return Action{}, fmt.Errorf("invalid action %d", i)
+	}
+
+	return envActions[i], nil
+}
+
+// Reset starts a new game and returns the first observation
+// Seed 0 plays a fixed seed as well, unlike games started with New.
+func (e *Env) Reset(seed int64) (*Observation, *Info, error) {
+	e.a.ClearCallbacks()
+
+	if seed == 0 {
+		seed = envSeed
+	}
+
+	var err error
+	e.g, err = New(e.a, &Options{
+		Seed:       seed,
+		Scene:      scenePlay,
+		Controller: e.agent,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Freeze the game once it is over
+	e.done = false
+	e.g.onGameOver = func() {
+		e.done = true
+		e.a.ClearCallbacks()
+	}
+
+	e.rows, e.cols = e.g.c.gridSize()
+
+	e.agent.act = Action{}
+	e.a.RunFrames(1)
+
+	obs, err := e.observe()
+
+	return obs, e.info(), err
+}
+
+// Step plays an action for FrameSkip ticks
+func (e *Env) Step(act Action) (obs *Observation, reward float64, done bool, info *Info, err error) {
+	if e.g == nil || e.done {
+		return nil, 0, true, nil, fmt.Errorf("game is over, reset the environment")
+	}
+
+	score, lifes := e.g.score, e.g.p.lifes
+
+	e.agent.act = act
+	e.a.RunFrames(e.c.FrameSkip)
+
+	// Aliens reaching the ground end the game without taking a life
+	lifesLost := max(0, lifes-e.g.p.lifes)
+	if e.done && lifesLost == 0 {
+		lifesLost = 1
+	}
+	reward = float64(e.g.score-score) - float64(lifesLost)*e.c.DeathPenalty
+
+	obs, err = e.observe()
+
+	return obs, reward, e.done, e.info(), err
+}
+
+// info returns information about the current game
+func (e *Env) info() *Info {
+	return &Info{
+		Score: e.g.score,
+		Level: e.g.level,
+		Lifes: e.g.p.lifes,
+		Ticks: e.g.ticks,
+	}
+}
+
+// observe returns an observation of the current game
+func (e *Env) observe() (*Observation, error) {
+	if e.c.Observation == ObserveFrame {
+		return e.observeFrame()
+	}
+
+	return e.observeVector(), nil
+}
+
+// observeVector returns the game state as a vector
+func (e *Env) observeVector() *Observation {
+	w := e.g.world()
+	nx := func(x int32) float64 { return float64(x) / float64(w.Width) }
+	ny := func(y int32) float64 { return float64(y) / float64(w.Height) }
+
+	v := []float64{
+		nx(w.Player.X + w.Player.W/2),
+		float64(w.Lifes) / float64(e.g.c.pc.lifes),
+	}
+
+	// Player bullet
+	if len(w.PlayerBullets) > 0 {
+		b := w.PlayerBullets[0]
+		v = append(v, nx(b.X), ny(b.Y))
+	} else {
+		v = append(v, -1, -1)
+	}
+
+	// Alien bullets, most dangerous first
+	ab := w.AlienBullets
+	sort.Slice(ab, func(i, j int) bool { return ab[i].Y > ab[j].Y })
+	for i := 0; i < envBullets; i++ {
+		if i < len(ab) {
+			v = append(v, nx(ab[i].X), ny(ab[i].Y))
+		} else {
+			v = append(v, -1, -1)
+		}
+	}
+
+	// Alien grid slots, all empty during boss waves
+	// The observation keeps the size of the largest grid, smaller grids leave
+	// the missing slots empty.
+	for row := 0; row < e.rows; row++ {
+		for col := 0; col < e.cols; col++ {
+			var a *alien
+			if row < len(e.g.ag.alienGridPos) && col < len(e.g.ag.alienGridPos[row]) {
+				a = e.g.ag.alienGridPos[row][col]
+			}
+			if a == nil {
+				v = append(v, 0, -1, -1)
+				continue
+			}
+			v = append(v, 1, nx(a.x+a.w/2), ny(a.y+a.h/2))
+		}
+	}
+
+	// Boss
+	if b := e.g.boss; b != nil {
+		v = append(v, float64(b.hp)/float64(b.maxHP), nx(b.x+b.w/2), ny(b.y+b.h/2))
+	} else {
+		v = append(v, 0, -1, -1)
+	}
+
+	return &Observation{Vector: v}
+}
+
+// observeFrame returns the current frame as a downsampled grayscale image
+func (e *Env) observeFrame() (*Observation, error) {
+	img, err := e.a.Capture()
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	obs := &Observation{
+		Frame:  make([]uint8, e.c.FrameWidth*e.c.FrameHeight),
+		Width:  e.c.FrameWidth,
+		Height: e.c.FrameHeight,
+	}
+
+	// Sample the center of each target pixel
+	for y := 0; y < obs.Height; y++ {
+		sy := b.Min.Y + (2*y+1)*b.Dy()/(2*obs.Height)
+		for x := 0; x < obs.Width; x++ {
+			sx := b.Min.X + (2*x+1)*b.Dx()/(2*obs.Width)
+			i := img.PixOffset(sx, sy)
+			r, g, bl := int(img.Pix[i]), int(img.Pix[i+1]), int(img.Pix[i+2])
+			obs.Frame[y*obs.Width+x] = uint8((299*r + 587*g + 114*bl) / 1000)
+		}
+	}
+
+	return obs, nil
+}
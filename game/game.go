@@ -2,128 +2,586 @@ package game
 
 import (
 	"fmt"
+	"log/slog"
+	"math/rand"
+	"path/filepath"
+	"time"
 
 	"github.com/MichaelThessel/spacee/app"
+	"github.com/MichaelThessel/spacee/collision"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 const (
 	// Game scene constants
-	sceneStart = "start"
-	scenePlay  = "play"
-	sceneEnd   = "end"
+	sceneStart   = "start"
+	scenePlay    = "play"
+	sceneEnd     = "end"
+	sceneReplay  = "replay"
+	sceneEditor  = "editor"
+	sceneOptions = "options"
 )
 
 // Game holds the game state
 type Game struct {
-	c     *Config
-	a     *app.App
-	scene string
-	start *start      // Start screen
-	end   *end        // End screen
-	p     *player     // Player
-	pbl   *bulletList // Player bullet list
-	abl   *bulletList // Alien bullet list
-	ag    *alienGrid  // Alien grid
-	stats *stats      // Game stats
-	score int         // Game score
+	c       *Config
+	o       *Options
+	a       *app.App
+	rng     *rand.Rand
+	src     *rngSource // Source of rng, holds its state
+	seed    int64      // Seed of the current game
+	scene   string
+	start   *start          // Start screen
+	end     *end            // End screen
+	p       *player         // Player
+	pbl     *bulletList     // Player bullet list
+	abl     *bulletList     // Alien bullet list
+	bunkers *bunkerList     // Bunkers of the current level
+	shelter *collision.Grid // Broadphase of the bunkers
+	ag      *alienGrid      // Alien grid
+	boss    *boss           // Boss (nil if the level isn't a boss wave)
+	stats   *stats          // Game stats
+	score   int             // Game score
+	level   int             // Current level
+	ticks   int             // Ticks played in the current game
+	ctrl    Controller      // Player controller
+	mode    *modeConfig     // Game mode
+	kills   int             // Aliens shot in the current game
+	rows    int             // Rows dropped in during the current game
+	rank    int             // High score rank of the last game (-1: not placed)
+
+	inputs   []byte          // Encoded player input of each tick
+	daily    *dailyChallenge // Daily challenge (nil outside of daily mode)
+	practice bool            // The day's scored attempt is used up
+
+	rp         *replay // Replay being watched
+	viewer     *viewer // Replay viewer
+	lastReplay string  // Replay file of the last game
+
+	resume *saveData // Saved game the play scene continues
+
+	levels  []*level // Level files
+	editor  *editor  // Level editor
+	testing bool     // Test playing the level of the editor
+
+	scripts *scripts // Mod scripts loaded from Options.Scripts
+	hooks   *scripts // Scripts of the current game (nil: none)
+
+	fileConfig *configData // Config loaded from Options.Config
+	settings   *settings   // Video, audio, gameplay & control options
+	theme      string      // Theme of Options.Theme, wins over the settings & is never saved
+	dev        *devWatcher // Reloads changed files in dev mode
+
+	debug   *debug // Debug overlay & console
+	cheated bool   // Console commands changed the current game
+
+	options     *options // Options screen
+	optionsBack string   // Scene the options go back to
+
+	slow    int       // Game speed accumulator of the speed assist
+	paused  bool      // The player paused the game
+	buttons []*button // Buttons of the play scene
+	pads    *pointer  // Mouse & touch input of a human player (nil: none)
+
+	// onGameOver replaces the end scene if set
+	onGameOver func()
+
+	maxTicks int  // Ticks after which a game is cut off (0: no limit)
+	timedOut bool // The current game was cut off after maxTicks
 }
 
 // Config holds game configuration
 type Config struct {
-	agc *alienGridConfig
-	pc  *playerConfig
+	agc    *alienGridConfig
+	pc     *playerConfig
+	bc     *bossConfig
+	levels []*level // Levels in order, later levels are generated
+
+	difficulty string      // Difficulty preset (empty: normal)
+	custom     *difficulty // Values of the custom difficulty (nil: normal values)
+	assists    assists     // Accessibility assists
+}
+
+// Options holds options to start a game with
+type Options struct {
+	Seed  int64  // Seed for the random number generator (0: random)
+	Scene string // Scene to start in ("start", "play" or "end")
+	Score int    // Score to start with
+	Bot   string // Bot that controls the player (empty: keyboard)
+	Mode  string // Game mode ("classic", "endless", "timeattack" or "daily")
+	Date  string // Daily challenge date as YYYY-MM-DD (empty: today)
+
+	// Replay is a replay file to watch, the game starts in the replay viewer
+	Replay string
+
+	// Levels is the directory of the level files (empty: assets/levels)
+	Levels string
+
+	// Level is the level file of the editor (empty: custom.json in the
+	// levels directory of the data dir)
+	Level string
+
+	// Scripts is a directory of Lua mod scripts (empty: none)
+	Scripts string
+
+	// Config is a JSON config file, missing values keep their defaults
+	Config string
+
+	// Dev reloads the config file and assets when they change
+	Dev bool
+
+	// DataDir is where high scores are saved (empty: user config dir)
+	DataDir string
+
+	// Controller controls the player, takes precedence over Bot
+	Controller Controller
+
+	// MouseAim moves the tank to the mouse cursor, clicks fire
+	MouseAim bool
+
+	// Theme is a built-in theme or a JSON theme file, it replaces the theme
+	// of the options for this run (empty: the options pick)
+	Theme string
 }
 
 // New returns a new game
-func New(a *app.App) (*Game, error) {
+func New(a *app.App, o *Options) (*Game, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	if o.Scene == "" {
+		o.Scene = sceneStart
+	}
+	if o.Mode == "" {
+		o.Mode = modeClassic
+	}
+	if o.Levels == "" {
+		o.Levels = "assets/levels"
+	}
+	if o.Seed == 0 {
+		o.Seed = time.Now().UnixNano()
+	}
+
 	g := &Game{
+		o:     o,
 		scene: sceneStart,
 		a:     a,
-		score: 0,
+		seed:  o.Seed,
+		rank:  -1,
+		score: o.Score,
 		pbl:   &bulletList{},
 		abl:   &bulletList{},
 		ag:    &alienGrid{},
+
+		bunkers: &bunkerList{},
+		shelter: collision.NewGrid(128),
+	}
+	g.rng, g.src = newRNG(o.Seed)
+
+	// Crash reports hold the game state and go next to the high scores
+	a.RegisterStateCallback("game", g.crashState)
+	if dir, err := dataDir(o); err == nil {
+		a.SetCrashDir(filepath.Join(dir, "crashes"))
+	}
+
+	// Sprites & sounds are shared by all games of the app
+	useAssets(a)
+
+	var err error
+	g.settings, err = loadSettings(o)
+	if err != nil {
+		return nil, err
+	}
+	if o.Theme != "" {
+		if _, err := loadTheme(o.Theme); err != nil {
+			return nil, err
+		}
+		g.theme = o.Theme
+	}
+	g.applySettings()
+	g.mapButtons()
+
+	g.levels, err = loadLevels(o.Levels)
+	if err != nil {
+		return nil, err
+	}
+	if o.Config != "" {
+		g.fileConfig, err = loadConfig(o.Config)
+		if err != nil {
+			return nil, err
+		}
 	}
 	g.initConfig()
 
-	if err := g.switchScene(sceneStart); err != nil {
+	if o.Dev {
+		g.dev = newDevWatcher(g)
+	}
+
+	if o.Scripts != "" {
+		g.scripts, err = loadScripts(o.Scripts, g.rng)
+		if err != nil {
+			return nil, err
+		}
+		a.Own(app.AppScope, g.scripts.Close)
+	}
+
+	g.mode, err = findMode(o.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.mode.daily {
+		g.daily, err = newDailyChallenge(o.Date)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Replay != "" {
+		if err := g.watchReplay(o.Replay); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+
+	if err := g.switchScene(o.Scene); err != nil {
 		return nil, err
 	}
 
 	return g, nil
 }
 
-// initConfig initalizes gthe game config
+// initConfig initalizes gthe game config, daily challenges ignore the config
+// file
 func (g *Game) initConfig() {
-	g.c = &Config{
+	daily := g.mode != nil && g.mode.daily
+
+	g.c = defaultConfig()
+	if g.fileConfig != nil && !daily {
+		g.c = g.fileConfig.config()
+	}
+	g.c.levels = g.levels
+
+	// Daily challenges are the same for everyone, bots play without the
+	// settings so their results stay comparable
+	player := g.o.Bot == "" && g.o.Controller == nil && g.settings != nil
+	if player && !daily {
+		g.c.assists = g.settings.Assists
+
+		// A difficulty in the config file wins over the settings
+		if d, err := findDifficulty(g.settings.Difficulty); err == nil && g.c.difficulty == "" {
+			g.c.difficulty = d.name
+			if d.name == difficultyCustom {
+				g.c.custom = g.settings.Custom.custom()
+			}
+		}
+	}
+
+	// The preset scales the player here and the grid with each level
+	g.c.preset().applyPlayer(g.c.pc)
+}
+
+// defaultConfig returns the default game config
+func defaultConfig() *Config {
+	return &Config{
 		agc: &alienGridConfig{
-			rows:        5,
-			cols:        10,
-			marginRow:   20,
-			marginCol:   20,
-			returnPoint: 30,
-			speedMax:    5,
-			speedStep:   3,
-			bulletSpeed: 15,
-			fireRate:    0.05,
-			stepSizeX:   10,
-			stepSizeY:   10,
+			rows:         5,
+			cols:         10,
+			marginRow:    20,
+			marginCol:    20,
+			returnPoint:  30,
+			speedMax:     5,
+			speedStep:    3,
+			bulletSpeed:  15,
+			fireRate:     0.05,
+			stepSizeX:    10,
+			stepSizeY:    10,
+			pixelPerfect: true,
+			rowTypes:     []string{"hunter", "splitter", "zigzagger", "grunt", "grunt"},
+
+			maxDivers:       3,
+			diveRates:       []float64{0, 0.005, 0.01, 0.015, 0.02},
+			diveFireRate:    0.03,
+			diveReturnTicks: 45,
 		},
 		pc: &playerConfig{
-			stepSize:    30,
-			bulletSpeed: 30,
-			lifes:       5,
+			stepSize:     30,
+			bulletSpeed:  30,
+			lifes:        5,
+			pixelPerfect: true,
+
+			respawnTicks:      45,
+			invulnerableTicks: 60,
+			extraLifes:        []int{5000, 15000},
+			extraLifeEvery:    20000,
+		},
+		bc: &bossConfig{
+			every:  5,
+			hp:     30,
+			hpStep: 15,
+			speed:  6,
+			points: 1000,
 		},
 	}
 }
 
+// changeScene switches the scene from a callback, the app stops if the scene
+// can't be set up
+func (g *Game) changeScene(scene string) {
+	if err := g.switchScene(scene); err != nil {
+		g.a.Fail(fmt.Errorf("couldn't switch to scene %s: %v", scene, err))
+	}
+}
+
 // switchScene switches to a different scene
 func (g *Game) switchScene(scene string) error {
 	g.a.ClearCallbacks()
+	g.a.CloseScene()
 
+	var err error
 	switch scene {
 	case sceneStart:
 		g.scene = sceneStart
-		return g.sceneStart()
+		err = g.sceneStart()
 	case scenePlay:
 		g.scene = scenePlay
-		return g.scenePlay()
+		err = g.scenePlay()
 	case sceneEnd:
 		g.scene = sceneEnd
-		return g.sceneEnd()
+		err = g.sceneEnd()
+	case sceneReplay:
+		g.scene = sceneReplay
+		err = g.sceneReplay()
+	case sceneEditor:
+		g.scene = sceneEditor
+		err = g.sceneEditor()
+	case sceneOptions:
+		g.scene = sceneOptions
+		err = g.sceneOptions()
 	default:
 		panic(fmt.Sprintf("Invalid scene %s", scene))
 	}
+
+	// Changed files are reloaded in every scene
+	if g.dev != nil {
+		g.a.RegisterUpdateCallback(g.dev.update)
+	}
+
+	return err
 }
 
 // sceneStart sets up the start screen
 func (g *Game) sceneStart() error {
 	// Start screen
 	var err error
-	g.start, err = newStart(g.a.GetRenderer())
+	titles := []string{}
+	selected := 0
+	for i, m := range modes {
+		titles = append(titles, m.title)
+		if m == g.mode {
+			selected = i
+		}
+	}
+
+	// A saved game comes first and is selected
+	resumable := g.hasSave()
+	if resumable {
+		titles = append([]string{"CONTINUE"}, titles...)
+		selected = 0
+	}
+
+	g.start, err = newStart(g.a.GetRenderer(), titles, selected)
 	if err != nil {
 		return err
 	}
+	g.a.Own(app.SceneScope, g.start.Close)
 
 	// Draw start screen
 	g.a.RegisterRenderCallback(1, g.start.Draw)
 
-	g.a.RegisterKeyCallback(sdl.K_RETURN, func() { g.switchScene(scenePlay) }) // start
+	play := func() {
+		i := g.start.selected
+		if resumable {
+			if i == 0 {
+				if err := g.continueGame(); err != nil {
+					slog.Error("couldn't continue game", "err", err)
+					g.changeScene(sceneStart)
+				}
+				return
+			}
+			i--
+		}
+
+		g.mode = modes[i]
+		g.newGame()
+	}
+	edit := func() { g.changeScene(sceneEditor) }
+
+	g.a.RegisterKeyCallback(sdl.K_LEFT, func() { g.start.selectMode(-1) }) // previous mode
+	g.a.RegisterKeyCallback(sdl.K_RIGHT, func() { g.start.selectMode(1) }) // next mode
+	g.a.RegisterKeyCallback(sdl.K_e, edit)                                 // level editor
+	g.a.RegisterKeyCallback(sdl.K_o, g.openOptions)                        // options
+	g.a.RegisterKeyCallback(sdl.K_RETURN, play)                            // start
+
+	// The same with the mouse
+	maxX, maxY := screenSize(g.a.GetRenderer())
+	x, y := int32(maxX)/2, int32(maxY)
+	g.start.buttons = []*button{
+		{label: "<", rect: sdl.Rect{X: x - 260, Y: y - 170, W: 50, H: 40}, click: func() { g.start.selectMode(-1) }},
+		{label: ">", rect: sdl.Rect{X: x + 210, Y: y - 170, W: 50, H: 40}, click: func() { g.start.selectMode(1) }},
+		{label: "START", rect: sdl.Rect{X: x - 320, Y: y - 80, W: 200, H: 44}, click: play},
+		{label: "OPTIONS", rect: sdl.Rect{X: x - 100, Y: y - 80, W: 200, H: 44}, click: g.openOptions},
+		{label: "EDITOR", rect: sdl.Rect{X: x + 120, Y: y - 80, W: 200, H: 44}, click: edit},
+	}
+	g.registerButtons(&g.start.buttons, nil)
 
 	return nil
 }
 
+// newGame starts a new game with a fresh seed in the current mode
+func (g *Game) newGame() {
+	g.seed = g.rng.Int63()
+	g.practice = false
+
+	if g.mode.daily {
+		if err := g.beginDaily(); err != nil {
+			slog.Error("couldn't set up daily challenge", "err", err)
+			return
+		}
+	}
+
+	g.changeScene(scenePlay)
+}
+
 // scenePlay sets up the game
 func (g *Game) scenePlay() error {
+	// Rules, daily challenges bring their own seed & modifiers and continued
+	// games their saved ones
+	if g.resume == nil {
+		g.initConfig()
+		if g.mode.daily && g.daily != nil {
+			g.seed = g.daily.seed
+			g.daily.apply(g.c)
+		}
+	}
+
+	// Mods don't take part in daily challenges
+	g.hooks = g.scripts
+	if g.mode.daily {
+		g.hooks = nil
+	}
+
+	// Test plays only have the level of the editor
+	if g.testing {
+		g.c.levels = []*level{g.editor.l}
+		g.a.RegisterKeyCallback(sdl.K_ESCAPE, func() { g.changeScene(sceneEditor) }) // back to the editor
+	}
+
+	if err := g.startGame(); err != nil {
+		return err
+	}
+
+	if g.resume != nil {
+		sv := g.resume
+		g.resume = nil
+		if err := g.restoreSave(sv); err != nil {
+			return err
+		}
+	}
+
+	// Players pause with the key or the button, the pause menu has the
+	// options
+	g.paused = false
+	g.buttons = nil
+	if g.o.Controller == nil {
+		g.buttons = g.playButtons()
+		g.a.RegisterKeyCallback(g.settings.key(actionPause), g.togglePause) // pause
+		g.a.RegisterKeyCallback(sdl.K_o, func() {                           // options
+			if g.paused {
+				g.openOptions()
+			}
+		})
+		if !g.testing {
+			g.a.RegisterKeyCallback(sdl.K_ESCAPE, func() { // menu
+				if g.paused {
+					g.leaveGame()
+				}
+			})
+		}
+	}
+
+	// Controller
+	var err error
+	var miss func(x, y int32, button uint8)
+	g.pads = nil
+	if g.o.Controller != nil {
+		g.ctrl = g.o.Controller
+	} else if g.o.Bot != "" {
+		g.ctrl, err = NewBot(g.o.Bot)
+		if err != nil {
+			return err
+		}
+	} else {
+		p := newPointer(g, g.o.MouseAim, &g.buttons)
+		g.ctrl, g.pads, miss = p, p, p.click
+	}
+	g.registerButtons(&g.buttons, miss)
+	g.a.RegisterRenderCallback(2, g.drawControls)
+
+	// Debug overlay & console, the state survives scenes but the font doesn't
+	if g.debug == nil {
+		g.debug = &debug{r: g.a.GetRenderer()}
+	}
+	if err := g.debug.open(); err != nil {
+		return err
+	}
+	g.a.Own(app.SceneScope, g.debug.Close)
+	g.debug.console, g.debug.paused, g.debug.steps = false, false, 0
+	g.a.RegisterRenderCallback(3, g.drawDebug)
+	g.a.RegisterKeyCallback(sdl.K_F1, g.toggleOverlay)        // overlay
+	g.a.RegisterKeyCallback(sdl.K_BACKQUOTE, g.toggleConsole) // console
+
+	// Players can continue where they quit
+	g.a.RegisterQuitCallback(g.saveProgress)
+
+	// Scripts reload when their files change
+	if g.hooks != nil {
+		reload := 0
+		g.a.RegisterUpdateCallback(func() {
+			reload++
+			// The replay only keeps the latest sources
+			if reload%max(1, int(g.a.GetFrameRate())) == 0 && g.hooks.reload() {
+				g.unscore("scripts reloaded")
+			}
+		})
+	}
+
+	// Advance the game unless it is paused
+	g.a.RegisterUpdateCallback(func() {
+		if !g.paused && g.advance() && g.debug.advance() {
+			g.tick()
+		}
+	})
+
+	return nil
+}
+
+// startGame sets up a new game with the current config & seed
+func (g *Game) startGame() error {
+	g.rng.Seed(g.seed)
+	g.inputs = g.inputs[:0]
+	g.cheated = false
+
 	// Player
 	var err error
 	g.p, err = newPlayer(g.a.GetRenderer(), g.c.pc)
 	if err != nil {
 		return err
 	}
+	g.p.margin = g.c.assists.HitMargin
 
 	// Start a new level
+	g.level = 0
 	err = g.startLevel(g.a.GetRenderer())
 	if err != nil {
 		return err
@@ -131,19 +589,22 @@ func (g *Game) scenePlay() error {
 
 	// Stats
 	g.score = 0
+	g.ticks = 0
+	g.timedOut = false
+	g.kills = 0
+	g.rows = 0
 	g.stats, err = newStats(g.a.GetRenderer(), g.c.pc.lifes)
 	if err != nil {
 		return err
 	}
-
-	// Keyboard
-	g.a.RegisterKeyCallback(sdl.K_LEFT, func() { g.p.Move('l') })    // left
-	g.a.RegisterKeyCallback(sdl.K_RIGHT, func() { g.p.Move('r') })   // right
-	g.a.RegisterKeyCallback(sdl.K_SPACE, func() { g.p.Fire(g.pbl) }) // fire
+	g.a.Own(app.SceneScope, g.stats.Close)
 
 	// Draw player
 	g.a.RegisterRenderCallback(1, g.p.Draw)
 
+	// Draw bunkers
+	g.a.RegisterRenderCallback(1, g.bunkers.Draw)
+
 	// Draw player & alien bullets
 	g.a.RegisterRenderCallback(1, g.abl.Draw)
 	g.a.RegisterRenderCallback(1, g.pbl.Draw)
@@ -151,68 +612,366 @@ func (g *Game) scenePlay() error {
 	// Draw alien grid
 	g.a.RegisterRenderCallback(1, g.ag.Draw)
 
-	// Draw stats
-	g.a.RegisterRenderCallback(1, func() { g.stats.Draw(g.p.lifes, g.score) })
+	// High contrast outlines
+	g.a.RegisterRenderCallback(1, g.drawContrast)
 
-	// Test if player bullets have hit
+	// Draw boss
 	g.a.RegisterRenderCallback(1, func() {
-		if hit, len := g.ag.testHit(g.pbl); hit {
-			g.score += 30
-			if len == 0 {
-				g.startLevel(g.a.GetRenderer())
-			}
+		if g.boss != nil {
+			g.boss.Draw()
 		}
 	})
 
-	// Test if alien bullets have hit
+	// Draw stats
 	g.a.RegisterRenderCallback(1, func() {
-		if g.p.testHit(g.abl) {
-			g.switchScene(sceneEnd)
+		g.stats.Draw(g.p.lifes, g.score)
+		if g.boss != nil {
+			g.stats.DrawHealth(g.boss.hp, g.boss.maxHP)
+		}
+		if g.mode.timeLimit > 0 {
+			g.stats.DrawTimer(g.timeLeft())
+		}
+		if g.hooks != nil && g.hooks.err != "" {
+			g.stats.DrawError("SCRIPT ERROR: " + g.hooks.err)
 		}
 	})
 
-	// Test if aliens have reached the ground
-	g.a.RegisterRenderCallback(1, func() {
-		if g.ag.testBoundary() {
-			g.switchScene(sceneEnd)
+	return nil
+}
+
+// tick advances the game by one tick
+func (g *Game) tick() {
+	g.ticks++
+	g.hooks.push(g)
+	defer g.hooks.pull(g)
+
+	// A respawning player gets a clean slate
+	if g.p.update() {
+		*g.abl = bulletList{}
+	}
+
+	// Aliens hold still while the player is down
+	paused := g.p.respawn > 0
+
+	// Player input
+	act := g.ctrl.Act(g.world())
+	if g.c.assists.AutoFire {
+		act.Fire = true
+	}
+	g.inputs = append(g.inputs, encodeAction(act))
+	if !paused {
+		if act.Left {
+			g.p.Move('l')
 		}
-	})
+		if act.Right {
+			g.p.Move('r')
+		}
+		if act.Fire {
+			g.p.Fire(g.pbl)
+		}
+	}
+
+	// Move aliens & bullets
+	if !paused {
+		if g.boss != nil {
+			g.boss.update(g.abl)
+		} else {
+			g.ag.update(g.p, g.abl)
+		}
+	}
+	g.abl.update(&point{x: float64(g.p.x + g.p.w/2), y: float64(g.p.y)})
+	g.pbl.update(nil)
+
+	// Bunkers stop bullets and crumble under aliens
+	g.bunkers.testHit(g.shelter, g.pbl)
+	g.bunkers.testHit(g.shelter, g.abl)
+	g.bunkers.erode(g.shelter, g.ag.alienList)
+
+	// Test if player bullets have hit
+	if g.boss != nil {
+		if points := g.boss.testHit(g.pbl); points > 0 {
+			g.score += g.hooks.onScore("boss", points)
+		}
+	} else if hit, _ := g.ag.testHit(g.pbl); hit {
+		g.score += g.hooks.onScore("alien", 30)
+		g.kills++
+	}
+	g.p.awardLifes(g.score)
+
+	// Fresh rows drop in from the top
+	if g.mode.rowTicks > 0 && g.ticks%g.mode.rowTicks == 0 && g.boss == nil {
+		names := alienTypeNames()
+		if g.ag.addRow(alienTypes[names[g.rng.Intn(len(names))]]) {
+			g.rows++
+		}
+	}
+
+	// Cut off games that don't end, like a bot that can't beat the boss
+	if g.maxTicks > 0 && g.ticks >= g.maxTicks {
+		g.timedOut = true
+		g.gameOver()
+		return
+	}
+
+	// Test if time is up
+	if g.mode.timeLimit > 0 && g.timeLeft() <= 0 {
+		g.gameOver()
+		return
+	}
+
+	// Test if alien bullets have hit
+	if g.p.testHit(g.abl) {
+		g.gameOver()
+		return
+	}
+
+	// Test if aliens have reached the ground
+	if g.ag.testBoundary() {
+		g.gameOver()
+		return
+	}
 
 	// Test if aliens collided with player
-	g.a.RegisterRenderCallback(1, func() {
-		if g.ag.testPlayerCollission(g.p) {
-			g.switchScene(sceneEnd)
+	if g.ag.testPlayerCollission(g.p) {
+		g.gameOver()
+		return
+	}
+
+	// Test if diving aliens crashed into the player
+	if g.ag.testDiverCollision(g.p) {
+		g.gameOver()
+		return
+	}
+
+	// Start the next level once all aliens or the boss are gone, test plays
+	// end with their level
+	if (g.boss != nil && g.boss.defeated()) || (g.boss == nil && len(g.ag.alienList) == 0) {
+		if g.testing {
+			g.changeScene(sceneEditor)
+			return
 		}
-	})
+		if err := g.startLevel(g.a.GetRenderer()); err != nil {
+			g.a.Fail(fmt.Errorf("couldn't start level %d: %v", g.level, err))
+			return
+		}
+	}
 
 	// Aliens fire
-	g.a.RegisterRenderCallback(1, func() { g.ag.fire(g.abl) })
+	if !paused {
+		g.ag.fire(g.abl)
+	}
+}
 
-	return nil
+// unscore marks the current game as changed while it runs, like console
+// commands it isn't scored, saved or replayable anymore
+func (g *Game) unscore(reason string) {
+	// Options opened from the pause menu return to the game
+	running := g.scene == scenePlay || g.scene == sceneOptions && g.optionsBack == scenePlay
+	if g.cheated || !running {
+		return
+	}
+	g.cheated = true
+	slog.Info("game isn't scored anymore", "reason", reason)
+}
+
+// gameOver ends the current game
+func (g *Game) gameOver() {
+	if g.onGameOver != nil {
+		g.onGameOver()
+		return
+	}
+
+	// Replays stop at their end
+	if g.scene == sceneReplay {
+		g.viewer.over = true
+		return
+	}
+
+	// Test plays aren't scored
+	if g.testing {
+		g.changeScene(sceneEditor)
+		return
+	}
+
+	// Games changed with the console aren't scored and can't be replayed
+	if g.cheated {
+		g.rank = -1
+		g.lastReplay = ""
+		g.changeScene(sceneEnd)
+		return
+	}
+
+	if err := g.saveScore(); err != nil {
+		slog.Error("couldn't save high score", "err", err)
+	}
+
+	var err error
+	g.lastReplay, err = g.saveGameReplay()
+	if err != nil {
+		slog.Error("couldn't save replay", "err", err)
+	}
+
+	if g.mode.daily {
+		if err := g.finishDaily(g.lastReplay); err != nil {
+			slog.Error("couldn't save daily challenge", "err", err)
+		}
+	}
+
+	g.changeScene(sceneEnd)
+}
+
+// saveScore adds the score of the current game to the high score table
+func (g *Game) saveScore() error {
+	g.rank = -1
+
+	if g.practice {
+		return nil
+	}
+
+	path, err := g.highScorePath()
+	if err != nil {
+		return err
+	}
+
+	hs, err := loadHighScores(path)
+	if err != nil {
+		return err
+	}
+
+	g.rank = hs.add(g.mode.name, highScore{
+		Score:      g.score,
+		Summary:    g.summary(),
+		Date:       time.Now(),
+		Difficulty: g.c.difficulty,
+		Assists:    g.c.assists.String(),
+	})
+
+	return hs.save(path)
+}
+
+// highScorePath returns the path of the high score file
+func (g *Game) highScorePath() (string, error) {
+	dir, err := dataDir(g.o)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "highscores.json"), nil
 }
 
 // sceneEnd sets up the end scene
 func (g *Game) sceneEnd() error {
+	res := &result{
+		mode:    g.mode.title,
+		score:   g.score,
+		summary: g.summary(),
+		rank:    g.rank,
+		replay:  g.lastReplay != "",
+	}
+
+	// Daily challenge results are shared in chat
+	if g.mode.daily && g.daily != nil {
+		if g.practice {
+			res.share = "PRACTICE RUN, TODAY'S ATTEMPT IS USED UP"
+		} else {
+			res.share = g.daily.result(g.score)
+			sdl.SetClipboardText(res.share)
+			slog.Info("daily challenge result", "result", res.share)
+		}
+	}
+
+	// The table is only decoration here, it is fine if it can't be loaded
+	if path, err := g.highScorePath(); err == nil {
+		if hs, err := loadHighScores(path); err == nil {
+			res.scores = hs[g.mode.name]
+		}
+	}
+
 	// End screen
 	var err error
-	g.end, err = newEnd(g.a.GetRenderer(), g.score)
+	g.end, err = newEnd(g.a.GetRenderer(), res)
 	if err != nil {
 		return err
 	}
+	g.a.Own(app.SceneScope, g.end.Close)
 
 	// Draw end screen
 	g.a.RegisterRenderCallback(1, g.end.Draw)
 
-	g.a.RegisterKeyCallback(sdl.K_RETURN, func() { g.switchScene(scenePlay) }) // start
+	menu := func() { g.changeScene(sceneStart) }
+	watch := func() {
+		if g.lastReplay == "" {
+			return
+		}
+		if err := g.watchReplay(g.lastReplay); err != nil {
+			slog.Error("couldn't watch replay", "err", err)
+		}
+	}
+
+	g.a.RegisterKeyCallback(sdl.K_RETURN, g.newGame) // restart
+	g.a.RegisterKeyCallback(sdl.K_ESCAPE, menu)      // menu
+	g.a.RegisterKeyCallback(sdl.K_r, watch)          // replay
+
+	// The same with the mouse
+	maxX, maxY := screenSize(g.a.GetRenderer())
+	x, y := int32(maxX)/2, int32(maxY)
+	g.end.buttons = []*button{
+		{label: "RESTART", rect: sdl.Rect{X: x - 210, Y: y - 80, W: 200, H: 44}, click: g.newGame},
+		{label: "MENU", rect: sdl.Rect{X: x + 10, Y: y - 80, W: 200, H: 44}, click: menu},
+	}
+	if res.replay {
+		for _, b := range g.end.buttons {
+			b.rect.X -= 110
+		}
+		g.end.buttons = append(g.end.buttons, &button{
+			label: "REPLAY", rect: sdl.Rect{X: x + 120, Y: y - 80, W: 200, H: 44}, click: watch,
+		})
+	}
+	g.registerButtons(&g.end.buttons, nil)
 
 	return nil
 }
 
 // startLevel starts a level
 func (g *Game) startLevel(r *sdl.Renderer) error {
-	// Reset alien grid
-	ag, err := newAlienGrid(r, g.c.agc)
-	*g.ag = *ag
+	g.level++
+
+	// Level files lay out the level, after them scripts may lay out the waves
+	lvl := g.c.level(g.level)
+	if lvl == nil {
+		lvl = g.hooks.onWave(g.level)
+	}
+	agc := g.levelConfig(lvl)
+
+	bunkers := 0
+	if lvl != nil {
+		bunkers = lvl.Bunkers
+	}
+	*g.bunkers = newBunkers(r, bunkers)
+	g.bunkers.index(g.shelter)
+
+	// Boss waves replace the alien grid with an empty one
+	var err error
+	g.boss = nil
+	if g.mode.bosses && g.c.bc.every > 0 && g.level%g.c.bc.every == 0 {
+		g.boss, err = newBoss(r, g.c.bc, g.c.agc.bulletSpeed, g.c.agc.pixelPerfect, g.rng, g.level/g.c.bc.every)
+		*g.ag = alienGrid{
+			c:     agc,
+			r:     r,
+			rng:   g.rng,
+			level: g.level,
+			broad: collision.NewGrid(128),
+			hooks: g.hooks,
+		}
+	} else {
+		// Reset alien grid
+		var ag *alienGrid
+		ag, err = newAlienGrid(r, agc, g.rng, g.level)
+		if err == nil {
+			ag.hooks = g.hooks
+			*g.ag = *ag
+		}
+	}
 
 	// Reset bullet list
 	bl := bulletList{}
@@ -221,3 +980,46 @@ func (g *Game) startLevel(r *sdl.Renderer) error {
 
 	return err
 }
+
+// levelConfig returns the alien grid config of the current level
+func (g *Game) levelConfig(lvl *level) *alienGridConfig {
+	// Level files replace the layout and settings
+	agc := *g.c.agc
+	if lvl != nil {
+		lvl.apply(&agc)
+	}
+
+	// The difficulty scales the level
+	g.c.preset().applyGrid(&agc)
+
+	// Aliens fire more often with each level in some modes
+	agc.fireRate *= 1 + g.mode.fireStep*float64(g.level-1)
+
+	return &agc
+}
+
+// crashState returns the game state for crash reports, the replay of the
+// running game reproduces it
+func (g *Game) crashState() any {
+	state := map[string]any{
+		"scene":  g.scene,
+		"mode":   g.mode.name,
+		"seed":   g.seed,
+		"level":  g.level,
+		"ticks":  g.ticks,
+		"score":  g.score,
+		"replay": g.replay(),
+	}
+
+	if g.p != nil {
+		state["lifes"] = g.p.lifes
+		state["aliens"] = len(g.ag.alienList)
+		state["playerBullets"] = len(*g.pbl)
+		state["alienBullets"] = len(*g.abl)
+	}
+	if g.boss != nil {
+		state["bossHP"] = g.boss.hp
+	}
+
+	return state
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// highScoreCount is the number of high scores kept per mode
+const highScoreCount = 10
+
+// highScore holds a high score entry
+type highScore struct {
+	Score   int       `json:"score"`
+	Summary string    `json:"summary"` // Mode specific results
+	Date    time.Time `json:"date"`
+
+	// The difficulty & assists the score was made with (empty: normal, none)
+	Difficulty string `json:"difficulty,omitempty"`
+	Assists    string `json:"assists,omitempty"`
+}
+
+// highScores holds the high score tables by mode
+type highScores map[string][]highScore
+
+// tags returns the difficulty & assists of a score, empty for normal games
+// without assists
+func (s highScore) tags() string {
+	var tags []string
+	if d, err := findDifficulty(s.Difficulty); err == nil && d.name != difficultyNormal {
+		tags = append(tags, d.title)
+	}
+	if s.Assists != "" {
+		tags = append(tags, s.Assists)
+	}
+
+	return strings.Join(tags, " ")
+}
+
+// dataDir returns the directory game data is stored in and creates it
+func dataDir(o *Options) (string, error) {
+	dir := o.DataDir
+	if dir == "" {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "lileinvaders")
+	}
+
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// loadHighScores loads the high score tables, a missing file is no error
+func loadHighScores(path string) (highScores, error) {
+	hs := highScores{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return hs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return hs, json.Unmarshal(data, &hs)
+}
+
+// save saves the high score tables
+func (hs highScores) save(path string) error {
+	data, err := json.MarshalIndent(hs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// add adds a score to a mode's table and returns its rank (-1: not placed)
+func (hs highScores) add(mode string, s highScore) int {
+	table := append(hs[mode], s)
+	sort.SliceStable(table, func(i, j int) bool { return table[i].Score > table[j].Score })
+
+	rank := -1
+	for i := range table {
+		if table[i] == s {
+			rank = i
+			break
+		}
+	}
+
+	if len(table) > highScoreCount {
+		table = table[:highScoreCount]
+	}
+	hs[mode] = table
+
+	if rank >= highScoreCount {
+		return -1
+	}
+
+	return rank
+}
@@ -0,0 +1,108 @@
+package game
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/MichaelThessel/spacee/app"
+)
+
+var leakCycles = flag.Int("leak-cycles", 5, "scene cycles of the leak test")
+
+func TestMain(m *testing.M) {
+	// SDL has to stay on the thread it was initialized on
+	runtime.LockOSThread()
+
+	// The game loads its assets relative to the repository root
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+// TestLeaks cycles through the scenes and checks that they free what they
+// load
+// The first cycle fills the shared asset cache, every later cycle has to end
+// with as many live textures, sounds & fonts as the first one. Closing all
+// resources of the app has to free everything.
+func TestLeaks(t *testing.T) {
+	quit, err := app.Init(true)
+	if err != nil {
+		t.Skipf("sdl isn't available: %v", err)
+	}
+	defer quit()
+
+	a, err := app.New(&app.Config{Width: 1200, Height: 800, Title: "e-Space", FrameRate: 30, Headless: true})
+	if err != nil {
+		t.Skipf("headless rendering isn't available: %v", err)
+	}
+	defer a.Destroy()
+
+	g, err := New(a, &Options{
+		Scene:   sceneStart,
+		Mode:    modeClassic,
+		Bot:     "track",
+		DataDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.RunFrames(5)
+
+	var live int64
+	var owned int
+	for i := 0; i < *leakCycles; i++ {
+		if err := g.leakCycle(); err != nil {
+			t.Fatalf("cycle %d: %v", i+1, err)
+		}
+
+		if i == 0 {
+			live, owned = liveResources.Load(), a.OpenResources()
+			continue
+		}
+		if n := liveResources.Load(); n != live {
+			t.Fatalf("cycle %d: %d live resources, %d after the first cycle", i+1, n, live)
+		}
+		if n := a.OpenResources(); n != owned {
+			t.Fatalf("cycle %d: %d registered resources, %d after the first cycle", i+1, n, owned)
+		}
+	}
+
+	a.CloseAll()
+	if n := liveResources.Load(); n != 0 {
+		t.Fatalf("%d resources left after closing the app", n)
+	}
+}
+
+// leakCycle plays a game through a few levels and the boss, then visits the
+// end screen, the editor & the start screen
+func (g *Game) leakCycle() error {
+	g.newGame()
+	g.a.RunFrames(30)
+
+	if _, err := g.debugSkip(nil); err != nil {
+		return err
+	}
+	if g.boss == nil {
+		if _, err := g.debugUFO(nil); err != nil {
+			return fmt.Errorf("couldn't spawn ufo: %v", err)
+		}
+	}
+	g.a.RunFrames(10)
+
+	// Cheated games go straight to the end screen without being saved
+	g.cheated = true
+	g.gameOver()
+	g.a.RunFrames(5)
+
+	for _, scene := range []string{sceneEditor, sceneStart} {
+		g.changeScene(scene)
+		g.a.RunFrames(5)
+	}
+
+	return g.a.Err()
+}
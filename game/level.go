@@ -0,0 +1,194 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// levelEmpty marks an empty slot in a level grid
+const levelEmpty = '.'
+
+// level describes the alien layout and settings of a level
+// The grid holds one string per row with an alien type symbol per slot (G:
+// grunt, Z: zigzagger, H: hunter, S: splitter, .: empty). Zero settings keep
+// the config values.
+type level struct {
+	Name        string   `json:"name,omitempty"`
+	Grid        []string `json:"grid"`
+	SpeedMax    int      `json:"speedMax,omitempty"`
+	SpeedStep   int      `json:"speedStep,omitempty"`
+	FireRate    float64  `json:"fireRate,omitempty"`
+	BulletSpeed int32    `json:"bulletSpeed,omitempty"`
+	Bunkers     int      `json:"bunkers"`
+}
+
+// newLevel returns a level with a full grid of the config's row types
+func newLevel(c *alienGridConfig, bunkers int) *level {
+	l := &level{Bunkers: bunkers}
+
+	slots, _ := c.slotTypes()
+	for _, row := range slots {
+		line := []byte{}
+		for _, typ := range row {
+			line = append(line, typ.symbol)
+		}
+		l.Grid = append(l.Grid, string(line))
+	}
+
+	return l
+}
+
+// loadLevel loads a level file
+func loadLevel(path string) (*level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load level: %v", err)
+	}
+
+	l := &level{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("couldn't parse level %s: %v", path, err)
+	}
+
+	if err := l.validate(); err != nil {
+		return nil, fmt.Errorf("invalid level %s: %v", path, err)
+	}
+
+	return l, nil
+}
+
+// loadLevels loads all level files of a directory ordered by name, a missing
+// directory has no levels
+func loadLevels(dir string) ([]*level, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	levels := []*level{}
+	for _, path := range paths {
+		l, err := loadLevel(path)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+
+	return levels, nil
+}
+
+// save saves the level as JSON
+func (l *level) save(path string) error {
+	if err := l.validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// validate checks the grid for unknown symbols
+func (l *level) validate() error {
+	if len(l.Grid) == 0 {
+		return fmt.Errorf("empty grid")
+	}
+
+	for row, line := range l.Grid {
+		for col := 0; col < len(line); col++ {
+			if _, typ := alienTypeBySymbol(line[col]); typ == nil && line[col] != levelEmpty {
+				return fmt.Errorf("unknown alien symbol %c at %d, %d", line[col], row, col)
+			}
+		}
+	}
+
+	if l.Bunkers < 0 {
+		return fmt.Errorf("negative number of bunkers")
+	}
+
+	return nil
+}
+
+// size returns the number of rows & columns of the grid
+func (l *level) size() (rows, cols int) {
+	for _, line := range l.Grid {
+		cols = max(cols, len(line))
+	}
+
+	return len(l.Grid), cols
+}
+
+// resize changes the grid size, new slots are empty
+func (l *level) resize(rows, cols int) {
+	grid := make([]string, rows)
+	for row := range grid {
+		line := ""
+		if row < len(l.Grid) {
+			line = l.Grid[row]
+		}
+		if len(line) > cols {
+			line = line[:cols]
+		}
+		grid[row] = line + strings.Repeat(string(levelEmpty), cols-len(line))
+	}
+	l.Grid = grid
+}
+
+// set sets the symbol of a slot
+func (l *level) set(row, col int, sym byte) {
+	line := []byte(l.Grid[row])
+	line[col] = sym
+	l.Grid[row] = string(line)
+}
+
+// apply applies the level to an alien grid config
+func (l *level) apply(c *alienGridConfig) {
+	c.rows, c.cols = l.size()
+	c.layout = l.Grid
+
+	if l.SpeedMax > 0 {
+		c.speedMax = l.SpeedMax
+	}
+	if l.SpeedStep > 0 {
+		c.speedStep = l.SpeedStep
+	}
+	if l.FireRate > 0 {
+		c.fireRate = l.FireRate
+	}
+	if l.BulletSpeed > 0 {
+		c.bulletSpeed = l.BulletSpeed
+	}
+}
+
+// level returns the level file of a level number or nil if there is none
+func (c *Config) level(n int) *level {
+	if n < 1 || n > len(c.levels) {
+		return nil
+	}
+
+	return c.levels[n-1]
+}
+
+// gridSize returns the size of the largest alien grid of the config & the
+// level files
+func (c *Config) gridSize() (rows, cols int) {
+	rows, cols = c.agc.rows, c.agc.cols
+	for _, l := range c.levels {
+		r, c := l.size()
+		rows, cols = max(rows, r), max(cols, c)
+	}
+
+	return rows, cols
+}
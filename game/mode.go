@@ -0,0 +1,82 @@
+package game
+
+import "fmt"
+
+const (
+	// Game mode constants
+	modeClassic    = "classic"
+	modeEndless    = "endless"
+	modeTimeAttack = "timeattack"
+	modeDaily      = "daily"
+)
+
+// modeConfig holds the rules of a game mode
+type modeConfig struct {
+	name      string
+	title     string
+	bosses    bool    // Boss waves
+	rowTicks  int     // Ticks between fresh rows dropping in (0: never)
+	timeLimit int     // Seconds until the game ends (0: no limit)
+	fireStep  float64 // Alien fire rate increase per level
+	daily     bool    // Seed & modifiers come from the date
+}
+
+// modes holds all game modes in menu order
+var modes = []*modeConfig{
+	{
+		name:   modeClassic,
+		title:  "CLASSIC",
+		bosses: true,
+	},
+	{
+		name:     modeEndless,
+		title:    "ENDLESS",
+		rowTicks: 300,
+		fireStep: 0.15,
+	},
+	{
+		name:      modeTimeAttack,
+		title:     "TIME ATTACK",
+		bosses:    true,
+		timeLimit: 180,
+	},
+	{
+		name:   modeDaily,
+		title:  "DAILY CHALLENGE",
+		bosses: true,
+		daily:  true,
+	},
+}
+
+// findMode returns the mode with the given name
+func findMode(name string) (*modeConfig, error) {
+	for _, m := range modes {
+		if m.name == name {
+			return m, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid mode %s", name)
+}
+
+// summary returns the mode specific results of the current game
+func (g *Game) summary() string {
+	switch g.mode.name {
+	case modeEndless:
+		return fmt.Sprintf("WAVE %d  ROWS %d", g.level, g.rows)
+	case modeTimeAttack:
+		return fmt.Sprintf("ALIENS %d  TIME %s", g.kills, clock(g.ticks/int(g.a.GetFrameRate())))
+	default:
+		return fmt.Sprintf("LEVEL %d", g.level)
+	}
+}
+
+// timeLeft returns the seconds left in a timed mode
+func (g *Game) timeLeft() int {
+	return g.mode.timeLimit - g.ticks/int(g.a.GetFrameRate())
+}
+
+// clock formats seconds as m:ss
+func clock(seconds int) string {
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,501 @@
+package game
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/MichaelThessel/spacee/app"
+	"github.com/veandco/go-sdl2/sdl"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// resolutions holds the selectable window sizes
+var resolutions = [][2]int{{960, 640}, {1200, 800}, {1440, 960}, {1800, 1200}, {1920, 1280}}
+
+// frameCaps holds the selectable frame caps (0: one frame per tick)
+var frameCaps = []int{0, 60, 120, 144}
+
+// option is a row of the options scene
+type option struct {
+	title  string
+	value  func() string
+	change func(step int) // Changes the value with left & right (optional)
+	pick   func()         // Runs on enter (optional, default: change forward)
+}
+
+// options holds the options screen state
+type options struct {
+	r         *sdl.Renderer
+	titleFont *ttf.Font
+	font      *ttf.Font
+	title     string
+	rows      []*option
+	buttons   []*button // One per row
+	selected  int
+	sub       bool   // A sub page is shown, going back shows the main rows
+	binding   string // Action waiting for a key (empty: none)
+	message   string
+}
+
+// newOptions returns a new options screen
+func newOptions(r *sdl.Renderer) (*options, error) {
+	o := &options{r: r}
+
+	var err error
+	o.titleFont, err = openFont(80)
+	if err != nil {
+		return nil, err
+	}
+
+	o.font, err = openFont(20)
+	if err != nil {
+		o.Close()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Close closes the fonts
+func (o *options) Close() {
+	closeFont(o.titleFont)
+	closeFont(o.font)
+}
+
+// Draw draws the options screen
+func (o *options) Draw() {
+	maxX, maxY := screenSize(o.r)
+	x := int32(maxX) / 2
+	c := themeColor(roleHUDText)
+
+	drawText(o.r, o.titleFont, o.title, x, 40, c)
+
+	for i, row := range o.rows {
+		y := o.buttons[i].rect.Y + 4
+		if i == o.selected {
+			drawTextRight(o.r, o.font, ">", x-260, y, c)
+		}
+		drawTextLeft(o.r, o.font, row.title, x-240, y, c)
+		if row.value != nil {
+			drawTextRight(o.r, o.font, row.value(), x+240, y, c)
+		}
+	}
+
+	help := "UP & DOWN SELECT, LEFT & RIGHT CHANGE, ENTER PICKS, ESC GOES BACK"
+	if o.message != "" {
+		help = o.message
+	}
+	drawText(o.r, o.font, help, x, int32(maxY)-60, c)
+}
+
+// move moves the selection by step
+func (o *options) move(step int) {
+	o.selected = (o.selected + step + len(o.rows)) % len(o.rows)
+	o.message = ""
+}
+
+// change changes the value of the selected row
+func (o *options) change(step int) {
+	if row := o.rows[o.selected]; row.change != nil {
+		row.change(step)
+	}
+}
+
+// pick picks the selected row
+func (o *options) pick() {
+	row := o.rows[o.selected]
+	switch {
+	case row.pick != nil:
+		row.pick()
+	case row.change != nil:
+		row.change(1)
+	}
+}
+
+// sceneOptions sets up the options scene
+func (g *Game) sceneOptions() error {
+	var err error
+	g.options, err = newOptions(g.a.GetRenderer())
+	if err != nil {
+		return err
+	}
+	g.a.Own(app.SceneScope, g.options.Close)
+	o := g.options
+	g.showOptions("OPTIONS", g.optionRows(), false)
+
+	// Draw options
+	g.a.RegisterRenderCallback(1, o.Draw)
+
+	g.a.RegisterKeyCallback(sdl.K_UP, func() { o.move(-1) })     // previous row
+	g.a.RegisterKeyCallback(sdl.K_DOWN, func() { o.move(1) })    // next row
+	g.a.RegisterKeyCallback(sdl.K_LEFT, func() { o.change(-1) }) // previous value
+	g.a.RegisterKeyCallback(sdl.K_RIGHT, func() { o.change(1) }) // next value
+	g.a.RegisterKeyCallback(sdl.K_RETURN, o.pick)                // pick
+	g.a.RegisterKeyCallback(sdl.K_ESCAPE, g.backOptions)         // back
+	g.registerButtons(&o.buttons, nil)
+
+	// A paused game is saved like from the play scene
+	if g.optionsBack == scenePlay {
+		g.a.RegisterQuitCallback(g.saveProgress)
+	}
+
+	return nil
+}
+
+// showOptions shows a page of rows
+func (g *Game) showOptions(title string, rows []*option, sub bool) {
+	o := g.options
+	o.title, o.rows, o.sub = title, rows, sub
+	o.selected, o.message = 0, ""
+
+	maxX, _ := screenSize(g.a.GetRenderer())
+	o.buttons = nil
+	for i := range o.rows {
+		i := i
+		o.buttons = append(o.buttons, &button{
+			rect: sdl.Rect{X: int32(maxX)/2 - 250, Y: 150 + int32(i)*38, W: 500, H: 34},
+			click: func() {
+				o.selected = i
+				o.pick()
+			},
+		})
+	}
+}
+
+// backOptions goes back from a sub page to the main rows or closes the
+// options
+func (g *Game) backOptions() {
+	if g.options.sub {
+		g.showOptions("OPTIONS", g.optionRows(), false)
+		return
+	}
+
+	g.closeOptions()
+}
+
+// nextGame returns a page title that notes when changes wait for the next
+// game
+func (g *Game) nextGame(title string) string {
+	if g.optionsBack == scenePlay {
+		return title + " (NEXT GAME)"
+	}
+
+	return title
+}
+
+// onOff formats a switch
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+
+	return "OFF"
+}
+
+// optionRows returns the main rows of the options scene
+func (g *Game) optionRows() []*option {
+	s := g.settings
+
+	resolution := func() int {
+		for i, r := range resolutions {
+			if r[0] == s.Width && r[1] == s.Height {
+				return i
+			}
+		}
+		return -1
+	}
+
+	rows := []*option{
+		{
+			title: "RESOLUTION",
+			value: func() string { return fmt.Sprintf("%dX%d", s.Width, s.Height) },
+			change: func(step int) {
+				r := resolutions[cycle(max(0, resolution()), step, len(resolutions))]
+				s.Width, s.Height = r[0], r[1]
+				g.changeSettings()
+			},
+		},
+		{
+			title:  "FULLSCREEN",
+			value:  func() string { return onOff(s.Fullscreen) },
+			change: func(int) { s.Fullscreen = !s.Fullscreen; g.changeSettings() },
+		},
+		{
+			title: "VSYNC",
+			value: func() string { return onOff(s.VSync) + " (NEXT START)" },
+			change: func(int) {
+				s.VSync = !s.VSync
+				g.changeSettings()
+			},
+		},
+		{
+			title: "FRAME CAP",
+			value: func() string {
+				if s.FrameCap == 0 {
+					return "OFF"
+				}
+				return fmt.Sprint(s.FrameCap)
+			},
+			change: func(step int) {
+				s.FrameCap = frameCaps[cycle(indexOf(frameCaps, s.FrameCap), step, len(frameCaps))]
+				g.changeSettings()
+			},
+		},
+		{
+			title:  "VOLUME",
+			value:  func() string { return fmt.Sprintf("%d%%", s.Volume) },
+			change: func(step int) { s.Volume = min(100, max(0, s.Volume+10*step)); g.changeSettings() },
+		},
+		{
+			title:  "EFFECTS",
+			value:  func() string { return fmt.Sprintf("%d%%", s.Effects) },
+			change: func(step int) { s.Effects = min(100, max(0, s.Effects+10*step)); g.changeSettings() },
+		},
+		{
+			// Theme files can be picked again after cycling past them
+			title: "THEME",
+			value: func() string { return activeTheme.title },
+			change: func(step int) {
+				current := activeTheme.name
+				names := make([]string, 0, len(themes)+1)
+				for _, t := range themes {
+					names = append(names, t.name)
+				}
+				if indexOf(names, current) == 0 && current != names[0] {
+					names = append(names, current)
+				}
+				s.Theme = names[cycle(indexOf(names, current), step, len(names))]
+
+				// Picking a theme ends the -theme override
+				g.theme = ""
+				g.changeSettings()
+			},
+		},
+		{
+			title: "DIFFICULTY",
+			value: func() string {
+				d, _ := findDifficulty(s.Difficulty)
+				if g.optionsBack == scenePlay {
+					return d.title + " (NEXT GAME)"
+				}
+				return d.title
+			},
+			change: func(step int) {
+				i := 0
+				for j, d := range difficulties {
+					if d.name == s.Difficulty {
+						i = j
+					}
+				}
+				s.Difficulty = difficulties[cycle(i, step, len(difficulties))].name
+				g.changeSettings()
+			},
+		},
+		{
+			title: "CUSTOM DIFFICULTY",
+			value: func() string { return ">" },
+			pick:  func() { g.showOptions(g.nextGame("CUSTOM"), g.customRows(), true) },
+		},
+		{
+			title: "ASSISTS",
+			value: func() string { return ">" },
+			pick:  func() { g.showOptions(g.nextGame("ASSISTS"), g.assistRows(), true) },
+		},
+	}
+
+	for _, action := range []string{actionLeft, actionRight, actionFire, actionPause} {
+		action := action
+		rows = append(rows, &option{
+			title: strings.ToUpper(action),
+			value: func() string {
+				if g.options.binding == action {
+					return "..."
+				}
+				return strings.ToUpper(s.Keys[action])
+			},
+			pick: func() { g.bindKey(action) },
+		})
+	}
+
+	return append(rows, &option{title: "BACK", pick: g.closeOptions})
+}
+
+// customRows returns the rows of the custom difficulty page, changing a
+// value picks the custom difficulty
+func (g *Game) customRows() []*option {
+	s := g.settings
+	d := &s.Custom
+	change := func() {
+		s.Difficulty = difficultyCustom
+		g.changeSettings()
+	}
+
+	// Factors change in steps of 0.1
+	factor := func(title string, v *float64, lo, hi float64) *option {
+		return &option{
+			title: title,
+			value: func() string { return fmt.Sprintf("%.1fX", *v) },
+			change: func(step int) {
+				*v = min(hi, max(lo, math.Round(*v*10+float64(step))/10))
+				change()
+			},
+		}
+	}
+
+	return []*option{
+		factor("ALIEN FIRE RATE", &d.FireRate, 0.2, 3),
+		factor("ALIEN BULLET SPEED", &d.BulletSpeed, 0.5, 2),
+		{
+			// Lower max speeds move faster
+			title: "ALIEN SPEED",
+			value: func() string { return fmt.Sprintf("%+d", -d.SpeedMax) },
+			change: func(step int) {
+				d.SpeedMax = min(3, max(-3, d.SpeedMax-step))
+				change()
+			},
+		},
+		factor("ALIEN DROP", &d.StepSizeY, 0.5, 2),
+		{
+			title: "LIFES",
+			value: func() string { return fmt.Sprintf("%+d", d.Lifes) },
+			change: func(step int) {
+				d.Lifes = min(5, max(-2, d.Lifes+step))
+				change()
+			},
+		},
+		{title: "BACK", pick: g.backOptions},
+	}
+}
+
+// assistRows returns the rows of the assists page
+func (g *Game) assistRows() []*option {
+	s := g.settings
+	a := &s.Assists
+
+	return []*option{
+		{
+			title: "GAME SPEED",
+			value: func() string { return fmt.Sprintf("%d%%", a.speed()) },
+			change: func(step int) {
+				a.Speed = gameSpeeds[cycle(indexOf(gameSpeeds, a.speed()), step, len(gameSpeeds))]
+				g.changeSettings()
+			},
+		},
+		{
+			title:  "AUTO-FIRE",
+			value:  func() string { return onOff(a.AutoFire) },
+			change: func(int) { a.AutoFire = !a.AutoFire; g.changeSettings() },
+		},
+		{
+			title: "HITBOX MARGIN",
+			value: func() string {
+				if a.HitMargin == 0 {
+					return "OFF"
+				}
+				return fmt.Sprintf("%d PX", a.HitMargin)
+			},
+			change: func(step int) {
+				a.HitMargin = hitMargins[cycle(indexOf(hitMargins, a.HitMargin), step, len(hitMargins))]
+				g.changeSettings()
+			},
+		},
+		{
+			title:  "HIGH CONTRAST",
+			value:  func() string { return onOff(a.HighContrast) },
+			change: func(int) { a.HighContrast = !a.HighContrast; g.changeSettings() },
+		},
+		{title: "BACK", pick: g.backOptions},
+	}
+}
+
+// cycle moves an index by step and wraps around
+func cycle(i, step, n int) int {
+	return (i + step + n) % n
+}
+
+// indexOf returns the index of a value in a list, 0 if it is missing
+func indexOf[T comparable](list []T, v T) int {
+	for i, item := range list {
+		if item == v {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// bindKey binds the next key pressed to an action, a key that is bound
+// already swaps places
+func (g *Game) bindKey(action string) {
+	o, s := g.options, g.settings
+	o.binding = action
+	o.message = fmt.Sprintf("PRESS A KEY FOR %s, ESC CANCELS", strings.ToUpper(action))
+
+	g.a.StartTextInput(func(key sdl.Keycode) {
+		switch key {
+		case sdl.K_ESCAPE:
+			o.message = ""
+		case sdl.K_q, sdl.K_RETURN, sdl.K_BACKQUOTE, sdl.K_F1:
+			o.message = fmt.Sprintf("%s IS TAKEN, PRESS ANOTHER KEY", strings.ToUpper(sdl.GetKeyName(key)))
+			return
+		default:
+			name := sdl.GetKeyName(key)
+			for other, k := range s.Keys {
+				if k == name {
+					s.Keys[other] = s.Keys[action]
+				}
+			}
+			s.Keys[action] = name
+			o.message = ""
+			g.mapButtons()
+			g.changeSettings()
+		}
+
+		o.binding = ""
+		g.a.StopTextInput()
+	}, func(string) {})
+}
+
+// openOptions opens the options scene, a game in progress is kept and
+// continues paused when the options close
+func (g *Game) openOptions() {
+	g.optionsBack = g.scene
+	if g.scene == scenePlay {
+		g.resume = g.save()
+	}
+
+	g.changeScene(sceneOptions)
+}
+
+// closeOptions goes back to the scene the options were opened from
+func (g *Game) closeOptions() {
+	if g.optionsBack != scenePlay {
+		g.changeScene(sceneStart)
+		return
+	}
+
+	// Continuing the game mustn't clear the console changes
+	cheated := g.cheated
+	if err := g.switchScene(scenePlay); err != nil {
+		slog.Error("couldn't continue game", "err", err)
+		g.resume = nil
+		g.changeScene(sceneStart)
+		return
+	}
+	g.cheated = cheated
+	g.togglePause()
+}
+
+// mapButtons maps the game controller buttons to keys
+func (g *Game) mapButtons() {
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_DPAD_UP, sdl.K_UP)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_DPAD_DOWN, sdl.K_DOWN)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_DPAD_LEFT, sdl.K_LEFT)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_DPAD_RIGHT, sdl.K_RIGHT)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_A, sdl.K_RETURN)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_B, sdl.K_ESCAPE)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_BACK, sdl.K_ESCAPE)
+	g.a.MapButton(sdl.CONTROLLER_BUTTON_START, g.settings.key(actionPause))
+}
@@ -2,35 +2,65 @@ package game
 
 import (
 	"fmt"
+	"math"
 
+	"github.com/MichaelThessel/spacee/collision"
 	"github.com/veandco/go-sdl2/sdl"
-	img "github.com/veandco/go-sdl2/sdl_image"
-	mix "github.com/veandco/go-sdl2/sdl_mixer"
 )
 
 // playerConfig holds the player configuration
 type playerConfig struct {
-	stepSize    int32
-	bulletSpeed int32
-	lifes       int
+	stepSize          int32
+	bulletSpeed       int32
+	lifes             int
+	pixelPerfect      bool  // Test hits against the sprite mask instead of the bounding box
+	respawnTicks      int   // Ticks between losing a life and respawning (0: no delay)
+	invulnerableTicks int   // Ticks the player can't be hit after respawning
+	extraLifes        []int // Scores that award an extra life
+	extraLifeEvery    int   // Score step of extra lifes after the last threshold (0: none)
+}
+
+// extraLifeScore returns the score that awards the nth extra life or -1 if
+// there is none
+func (c *playerConfig) extraLifeScore(n int) int {
+	if n < len(c.extraLifes) {
+		return c.extraLifes[n]
+	}
+	if c.extraLifeEvery <= 0 {
+		return -1
+	}
+
+	last := 0
+	if len(c.extraLifes) > 0 {
+		last = c.extraLifes[len(c.extraLifes)-1]
+	}
+
+	return last + (n-len(c.extraLifes)+1)*c.extraLifeEvery
 }
 
 // player holds the player state
 type player struct {
 	c      *playerConfig
 	r      *sdl.Renderer
-	t      *sdl.Texture
-	sounds map[string]*mix.Chunk
+	s      *sprite
+	sounds map[string]*sound
 	x      int32
 	y      int32
 	w      int32
 	h      int32
 	lifes  int
+
+	respawn      int   // Ticks until the player respawns (0: in play)
+	respawned    bool  // Respawned without delay since the last update
+	invulnerable int   // Ticks the player can't be hit
+	extraLifes   int   // Extra lifes awarded so far
+	god          bool  // Can't be hit (debug console)
+	margin       int32 // Pixels the hitbox shrinks by on each side (assist)
 }
 
 // newPlayer generates a player
 func newPlayer(r *sdl.Renderer, c *playerConfig) (*player, error) {
-	maxX, maxY, _ := r.GetRendererOutputSize()
+	maxX, maxY := screenSize(r)
 	p := &player{
 		c:     c,
 		r:     r,
@@ -41,7 +71,7 @@ func newPlayer(r *sdl.Renderer, c *playerConfig) (*player, error) {
 
 	// Set texture
 	var err error
-	p.t, err = img.LoadTexture(r, "assets/tank.png")
+	p.s, err = loadSprite(r, "assets/tank.png")
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create player texture: %v", err)
 	}
@@ -51,14 +81,12 @@ func newPlayer(r *sdl.Renderer, c *playerConfig) (*player, error) {
 	p.y = int32(maxY) - p.h
 
 	// Set sounds
-	p.sounds = make(map[string]*mix.Chunk, 0)
-	p.sounds["fire"], err = mix.LoadWAV("assets/sounds/fire.wav")
-	if err != nil {
-		return nil, fmt.Errorf("couldn't load sound: %v", err)
+	p.sounds = make(map[string]*sound, 0)
+	if err := loadSound(r, p.sounds, "fire", "assets/sounds/fire.wav"); err != nil {
+		return nil, err
 	}
-	p.sounds["hit"], err = mix.LoadWAV("assets/sounds/playerhit.wav")
-	if err != nil {
-		return nil, fmt.Errorf("couldn't load sound: %v", err)
+	if err := loadSound(r, p.sounds, "hit", "assets/sounds/playerhit.wav"); err != nil {
+		return nil, err
 	}
 
 	return p, nil
@@ -66,12 +94,122 @@ func newPlayer(r *sdl.Renderer, c *playerConfig) (*player, error) {
 
 // Draw draws the player
 func (p *player) Draw() {
-	p.r.Copy(p.t, nil, &sdl.Rect{X: p.x, Y: p.y, W: p.w, H: p.h})
+	if p.respawn > 0 {
+		p.drawExplosion()
+		return
+	}
+
+	// Blink while invulnerable
+	if p.invulnerable > 0 && p.invulnerable/4%2 == 1 {
+		return
+	}
+
+	p.r.Copy(p.s.t, nil, &sdl.Rect{X: p.x, Y: p.y, W: p.w, H: p.h})
+}
+
+// drawExplosion draws debris flying apart and fading out while the player
+// waits to respawn
+func (p *player) drawExplosion() {
+	t := 1 - float64(p.respawn)/float64(p.c.respawnTicks)
+	cx, cy := float64(p.x+p.w/2), float64(p.y+p.h/2)
+	size := int32(12 - 8*t)
+
+	p.r.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
+	c := themeColor(rolePlayerBullet)
+	p.r.SetDrawColor(c.R, c.G, c.B, uint8(0xFF*(1-t)))
+	for i := 0; i < 12; i++ {
+		angle := 2 * math.Pi * float64(i) / 12
+		dist := 20 + 100*t*(0.6+0.4*float64(i%3)/2)
+		p.r.FillRect(&sdl.Rect{
+			X: int32(cx+dist*math.Cos(angle)) - size/2,
+			Y: int32(cy-dist*math.Abs(math.Sin(angle))) - size/2,
+			W: size,
+			H: size,
+		})
+	}
+	p.r.SetDrawBlendMode(sdl.BLENDMODE_NONE)
+}
+
+// box returns the player bounding box
+func (p *player) box() collision.Box {
+	return collision.Box{X: p.x, Y: p.y, W: p.w, H: p.h}
+}
+
+// hitBox returns the box & mask bullets and divers are tested against, a
+// forgiveness margin shrinks the box and drops the mask
+func (p *player) hitBox() (collision.Box, *collision.Mask) {
+	if p.margin <= 0 {
+		return p.box(), p.mask()
+	}
+
+	return collision.Box{
+		X: p.x + p.margin,
+		Y: p.y + p.margin,
+		W: max(1, p.w-2*p.margin),
+		H: max(1, p.h-2*p.margin),
+	}, nil
+}
+
+// mask returns the player collision mask or nil if hits are tested against
+// the bounding box
+func (p *player) mask() *collision.Mask {
+	if !p.c.pixelPerfect {
+		return nil
+	}
+
+	return p.s.m
+}
+
+// update counts down the respawn delay and the invulnerability and returns true
+// when the player respawns
+func (p *player) update() bool {
+	if p.respawned {
+		p.respawned = false
+		return true
+	}
+
+	if p.invulnerable > 0 {
+		p.invulnerable--
+	}
+
+	if p.respawn == 0 {
+		return false
+	}
+
+	p.respawn--
+	if p.respawn > 0 {
+		return false
+	}
+
+	// Back in the middle of the screen
+	maxX, _ := screenSize(p.r)
+	p.x = int32(maxX)/2 - p.w/2
+	p.invulnerable = p.c.invulnerableTicks
+
+	return true
+}
+
+// vulnerable checks if the player can be hit
+func (p *player) vulnerable() bool {
+	return p.respawn == 0 && p.invulnerable == 0 && !p.god
+}
+
+// awardLifes awards the extra lifes the score has reached
+func (p *player) awardLifes(score int) {
+	for {
+		next := p.c.extraLifeScore(p.extraLifes)
+		if next < 0 || score < next {
+			return
+		}
+
+		p.lifes++
+		p.extraLifes++
+	}
 }
 
 // Move moves the player in a given direction
 func (p *player) Move(direction rune) {
-	maxX, _, _ := p.r.GetRendererOutputSize()
+	maxX, _ := screenSize(p.r)
 	switch direction {
 	case 'l':
 		p.x -= p.c.stepSize
@@ -100,9 +238,7 @@ func (p *player) Fire(bullets *bulletList) {
 		&bulletConfig{
 			speed:     p.c.bulletSpeed,
 			direction: -1,
-			colorR:    0x00,
-			colorG:    0xFC,
-			colorB:    0xFF,
+			color:     rolePlayerBullet,
 		},
 	)
 
@@ -111,18 +247,20 @@ func (p *player) Fire(bullets *bulletList) {
 
 // test hit checks if a bullet has hit player
 func (p *player) testHit(bl *bulletList) (dead bool) {
-	for _, b := range *bl {
+	for i := 0; i < len(*bl) && p.vulnerable(); i++ {
+		b := (*bl)[i]
+
 		// Continue if bullet is beyond player dimensions
-		if b.y+b.h < p.y || b.x+b.w < p.x || b.x > p.x+p.w {
+		box, mask := p.hitBox()
+		if !collision.Overlaps(box, mask, b.box(), nil) {
 			continue
 		}
 
+		// The removal shifts the next bullet into this slot
 		bl.remove(b)
+		i--
 
-		p.sounds["hit"].Play(0, 0)
-
-		p.lifes--
-		if p.lifes == 0 {
+		if p.hit() {
 			dead = true
 			return
 		}
@@ -130,3 +268,26 @@ func (p *player) testHit(bl *bulletList) (dead bool) {
 
 	return
 }
+
+// hit takes a life from the player and returns true if the player is dead
+// Otherwise the player explodes and respawns after the respawn delay.
+func (p *player) hit() bool {
+	p.sounds["hit"].Play(0, 0)
+
+	p.lifes--
+	if p.lifes <= 0 {
+		return true
+	}
+
+	// Without a delay the player respawns in place right away, the next
+	// update reports the respawn so the bullets are cleared all the same
+	if p.c.respawnTicks == 0 {
+		p.invulnerable = p.c.invulnerableTicks
+		p.respawned = true
+		return false
+	}
+
+	p.respawn = p.c.respawnTicks
+
+	return false
+}
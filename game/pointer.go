@@ -0,0 +1,176 @@
+package game
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Virtual buttons of touch screens
+const (
+	padLeft  = "<"
+	padRight = ">"
+	padFire  = "FIRE"
+)
+
+// pointer is the controller of a human player, it adds the mouse and touch
+// screens to the keyboard
+// In mouse aim mode the tank follows the cursor and a click fires. Touch
+// screens get virtual buttons once they are touched.
+type pointer struct {
+	k       *keyboard
+	aim     bool             // Mouse aim mode
+	x       int32            // Cursor x in mouse aim mode (-1: not moved yet)
+	fire    bool             // Clicked or tapped since the last tick
+	touched bool             // The screen has been touched, show the pads
+	pads    []*button        // Virtual buttons
+	fingers map[int64]string // Pad held by each finger
+	buttons *[]*button       // Scene buttons that can be tapped
+}
+
+// newPointer returns a pointer controller for the play scene, taps on the
+// scene buttons click them
+func newPointer(g *Game, aim bool, buttons *[]*button) *pointer {
+	maxX, maxY := screenSize(g.a.GetRenderer())
+	p := &pointer{
+		k:       newKeyboard(g.a, g.settings),
+		aim:     aim,
+		x:       -1,
+		fingers: map[int64]string{},
+		buttons: buttons,
+		pads: []*button{
+			{label: padLeft, rect: sdl.Rect{X: 20, Y: int32(maxY) - 140, W: 120, H: 120}},
+			{label: padRight, rect: sdl.Rect{X: 160, Y: int32(maxY) - 140, W: 120, H: 120}},
+			{label: padFire, rect: sdl.Rect{X: int32(maxX) - 140, Y: int32(maxY) - 140, W: 120, H: 120}},
+		},
+	}
+
+	g.a.RegisterMotionCallback(p.move)
+	g.a.RegisterTouchCallback(p.touch)
+
+	return p
+}
+
+// move follows the cursor in mouse aim mode
+func (p *pointer) move(x, y int32) {
+	if p.aim {
+		p.x = x
+	}
+}
+
+// click fires in mouse aim mode
+func (p *pointer) click(x, y int32, button uint8) {
+	if p.aim && button == sdl.BUTTON_LEFT {
+		p.fire = true
+	}
+}
+
+// touch tracks the pads held by the fingers, fingers can slide from one pad
+// to another
+func (p *pointer) touch(finger int64, x, y int32, typ uint32) {
+	p.touched = true
+
+	if typ == sdl.FINGERUP {
+		delete(p.fingers, finger)
+		return
+	}
+
+	pad := findButton(p.pads, x, y)
+	if pad == nil {
+		delete(p.fingers, finger)
+		if b := findButton(*p.buttons, x, y); b != nil && typ == sdl.FINGERDOWN {
+			b.click()
+		}
+		return
+	}
+
+	p.fingers[finger] = pad.label
+	if pad.label == padFire && typ == sdl.FINGERDOWN {
+		p.fire = true
+	}
+}
+
+// Act returns the pressed keys, held pads and the mouse input since the last
+// tick
+func (p *pointer) Act(w *World) Action {
+	a := p.k.Act(w)
+
+	for _, pad := range p.fingers {
+		switch pad {
+		case padLeft:
+			a.Left = true
+		case padRight:
+			a.Right = true
+		case padFire:
+			a.Fire = true
+		}
+	}
+
+	if p.fire {
+		a.Fire = true
+		p.fire = false
+	}
+
+	// Move towards the cursor unless it is within a step of the tank center
+	if p.aim && p.x >= 0 && !a.Left && !a.Right {
+		center := w.Player.X + w.Player.W/2
+		a.Left = p.x < center-w.PlayerStep/2
+		a.Right = p.x > center+w.PlayerStep/2
+	}
+
+	return a
+}
+
+// togglePause pauses or resumes the game
+func (g *Game) togglePause() {
+	g.paused = !g.paused
+	g.buttons = g.playButtons()
+}
+
+// playButtons returns the buttons of the play scene, the pause menu while
+// paused
+func (g *Game) playButtons() []*button {
+	maxX, maxY := screenSize(g.a.GetRenderer())
+	x, y := int32(maxX)/2, int32(maxY)/2
+
+	buttons := []*button{
+		{label: "II", rect: sdl.Rect{X: x - 30, Y: 10, W: 60, H: 40}, click: g.togglePause},
+	}
+	if g.paused {
+		buttons = append(buttons,
+			&button{label: "RESUME", rect: sdl.Rect{X: x - 100, Y: y + 40, W: 200, H: 44}, click: g.togglePause},
+			&button{label: "OPTIONS", rect: sdl.Rect{X: x - 100, Y: y + 100, W: 200, H: 44}, click: g.openOptions},
+			&button{label: "MENU", rect: sdl.Rect{X: x - 100, Y: y + 160, W: 200, H: 44}, click: g.leaveGame},
+		)
+	}
+
+	return buttons
+}
+
+// leaveGame goes back to the menu from the pause menu, the game can be
+// continued like after quitting
+func (g *Game) leaveGame() {
+	if g.testing {
+		g.changeScene(sceneEditor)
+		return
+	}
+
+	g.saveProgress()
+	g.changeScene(sceneStart)
+}
+
+// drawControls draws the play scene buttons, the pads of touch screens and
+// the pause notice
+func (g *Game) drawControls() {
+	r := g.a.GetRenderer()
+	c := themeColor(roleHUDText)
+
+	drawButtons(r, g.stats.small, g.buttons, c)
+	if g.pads != nil && g.pads.touched {
+		drawButtons(r, g.stats.small, g.pads.pads, c)
+	}
+
+	if g.paused {
+		maxX, maxY := screenSize(r)
+		drawText(r, g.stats.font, "PAUSED", int32(maxX)/2, int32(maxY)/2-60, c)
+		drawText(r, g.stats.small, "P RESUMES, O OPENS THE OPTIONS, ESC GOES TO THE MENU", int32(maxX)/2, int32(maxY)/2+10, c)
+	}
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// bulletBehavior moves a bullet on each tick
+// It returns false if the bullet is gone and any bullets it spawned.
+type bulletBehavior interface {
+	move(b *bullet, target *point) (bool, []*bullet)
+}
+
+// straight moves bullets in a straight line
+type straight struct{}
+
+func (straight) move(b *bullet, target *point) (bool, []*bullet) {
+	b.fx += b.vx
+	b.fy += float64(b.c.direction * b.c.speed)
+
+	return true, nil
+}
+
+// zigzag moves bullets along a sine wave around their launch position
+type zigzag struct {
+	amplitude float64 // Max horizontal offset
+	period    int     // Ticks per wave
+}
+
+func (z zigzag) move(b *bullet, target *point) (bool, []*bullet) {
+	b.fx = b.ox + z.amplitude*math.Sin(2*math.Pi*float64(b.age)/float64(z.period))
+	b.fy += float64(b.c.direction * b.c.speed)
+
+	return true, nil
+}
+
+// homing slowly steers bullets towards the target
+type homing struct {
+	turn     float64 // Horizontal acceleration per tick
+	maxSpeed float64 // Max horizontal speed
+}
+
+func (h homing) move(b *bullet, target *point) (bool, []*bullet) {
+	if target != nil {
+		if target.x > b.fx+float64(b.w)/2 {
+			b.vx = math.Min(b.vx+h.turn, h.maxSpeed)
+		} else {
+			b.vx = math.Max(b.vx-h.turn, -h.maxSpeed)
+		}
+	}
+
+	return straight{}.move(b, target)
+}
+
+// splitting bullets fly straight for a while and then burst into fragments
+type splitting struct {
+	after     int     // Ticks until the bullet splits
+	fragments int     // Number of fragments
+	spread    float64 // Horizontal speed difference between fragments
+}
+
+func (s splitting) move(b *bullet, target *point) (bool, []*bullet) {
+	straight{}.move(b, target)
+
+	if b.age < s.after {
+		return true, nil
+	}
+
+	// Fragments are plain bullets with the shell's color
+	fc := &bulletConfig{
+		speed:     b.c.speed,
+		direction: b.c.direction,
+		color:     b.c.color,
+	}
+
+	fragments := make([]*bullet, s.fragments)
+	for i := range fragments {
+		f := makeBullet(b.r, b.x+b.w/2, b.y+b.h/2, fc)
+		f.vx = s.spread * (float64(i) - float64(s.fragments-1)/2)
+		fragments[i] = f
+	}
+
+	return false, fragments
+}
+
+// projectile describes a type of projectile aliens can fire
+type projectile struct {
+	speed    float64 // Speed relative to the alien bullet speed
+	w        int32   // Hitbox size
+	h        int32
+	color    string // Theme role of the color
+	sprite   string // Sprite path (empty: filled rectangle)
+	behavior bulletBehavior
+}
+
+// projectiles holds all alien projectile types by name
+var projectiles = map[string]*projectile{
+	"straight": {
+		speed:    1,
+		w:        7,
+		h:        9,
+		color:    roleEnemyBullet,
+		behavior: straight{},
+	},
+	"zigzag": {
+		speed:    0.7,
+		w:        7,
+		h:        9,
+		color:    roleZigzagBullet,
+		behavior: zigzag{amplitude: 25, period: 20},
+	},
+	"homing": {
+		speed:    0.5,
+		w:        9,
+		h:        9,
+		color:    roleHomingBullet,
+		behavior: homing{turn: 0.4, maxSpeed: 6},
+	},
+	"split": {
+		speed:    0.6,
+		w:        20,
+		h:        22,
+		color:    roleEnemyBullet,
+		sprite:   "assets/alien.png",
+		behavior: splitting{after: 25, fragments: 3, spread: 4},
+	},
+}
+
+// loadWeapons returns bullet configs for the given projectiles
+// Sprites are loaded once and shared.
+func loadWeapons(r *sdl.Renderer, names []string, speed int32, sprites map[string]*sprite) (map[string]*bulletConfig, error) {
+	weapons := make(map[string]*bulletConfig)
+
+	for _, name := range names {
+		if _, ok := weapons[name]; ok {
+			continue
+		}
+
+		p, ok := projectiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown projectile %s", name)
+		}
+
+		c := &bulletConfig{
+			speed:     int32(math.Max(1, math.Round(float64(speed)*p.speed))),
+			direction: 1,
+			color:     p.color,
+			w:         p.w,
+			h:         p.h,
+			behavior:  p.behavior,
+		}
+
+		if p.sprite != "" {
+			if sprites[p.sprite] == nil {
+				s, err := loadSprite(r, p.sprite)
+				if err != nil {
+					return nil, err
+				}
+				sprites[p.sprite] = s
+			}
+			c.s = sprites[p.sprite]
+		}
+
+		weapons[name] = c
+	}
+
+	return weapons, nil
+}
@@ -0,0 +1,155 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// replayVersion is the version of the replay format
+// Version 2 switched the game to the splitmix source keyframes need and added
+// the config snapshot. Version 1 replays were recorded with the math/rand
+// source, their inputs play a different game so they are rejected.
+const replayVersion = 2
+
+// replay holds everything needed to replay a game
+type replay struct {
+	Version int         `json:"version"`
+	Mode    string      `json:"mode"`
+	Seed    int64       `json:"seed"`
+	Date    string      `json:"date,omitempty"` // Daily challenge date
+	Mods    []string    `json:"mods,omitempty"` // Daily challenge modifiers
+	Score   int         `json:"score"`
+	Config  *configData `json:"config,omitempty"` // Config the game was played with
+	Inputs  string      `json:"inputs"`           // One hex digit per tick (1: left, 2: right, 4: fire)
+
+	// Scripts holds the sources of the mod scripts the game was played with
+	Scripts map[string]string `json:"scripts,omitempty"`
+}
+
+// encodeAction encodes an action as hex digit
+func encodeAction(a Action) byte {
+	var d byte
+	if a.Left {
+		d |= 1
+	}
+	if a.Right {
+		d |= 2
+	}
+	if a.Fire {
+		d |= 4
+	}
+
+	return "0123456789abcdef"[d]
+}
+
+// decodeAction decodes an action from a hex digit
+func decodeAction(c byte) Action {
+	var d byte
+	switch {
+	case c >= '0' && c <= '9':
+		d = c - '0'
+	case c >= 'a' && c <= 'f':
+		d = c - 'a' + 10
+	}
+
+	return Action{Left: d&1 != 0, Right: d&2 != 0, Fire: d&4 != 0}
+}
+
+// replayer is a controller that plays back recorded inputs
+type replayer struct {
+	inputs string
+	tick   int
+}
+
+// Act implements Controller
+func (r *replayer) Act(w *World) Action {
+	if r.tick >= len(r.inputs) {
+		return Action{}
+	}
+	r.tick++
+
+	return decodeAction(r.inputs[r.tick-1])
+}
+
+// replay returns a replay of the current game
+func (g *Game) replay() *replay {
+	rp := &replay{
+		Version: replayVersion,
+		Mode:    g.mode.name,
+		Seed:    g.seed,
+		Score:   g.score,
+		Config:  g.c.data(),
+		Inputs:  string(g.inputs),
+	}
+
+	if g.mode.daily && g.daily != nil {
+		rp.Date = g.daily.date
+		rp.Mods = g.daily.mods
+	}
+
+	if g.hooks != nil {
+		rp.Scripts = g.hooks.sources
+	}
+
+	return rp
+}
+
+// saveReplay saves a replay as JSON
+func saveReplay(path string, rp *replay) error {
+	data, err := json.Marshal(rp)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadReplay loads a replay
+func loadReplay(path string) (*replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &replay{}
+	if err := json.Unmarshal(data, rp); err != nil {
+		return nil, fmt.Errorf("couldn't parse replay: %v", err)
+	}
+
+	// The first replays were written without a version
+	if rp.Version == 0 {
+		rp.Version = 1
+	}
+	if rp.Version < 2 {
+		return nil, fmt.Errorf("replay version %d was recorded with the old random source and can't be played back anymore", rp.Version)
+	}
+	if rp.Version > replayVersion {
+		return nil, fmt.Errorf("replay version %d is newer than the supported version %d", rp.Version, replayVersion)
+	}
+	if rp.Config == nil {
+		return nil, fmt.Errorf("replay has no config snapshot")
+	}
+
+	return rp, nil
+}
+
+// saveGameReplay saves the replay of the current game next to the high scores
+// and returns its path
+func (g *Game) saveGameReplay() (string, error) {
+	dir, err := dataDir(g.o)
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Join(dir, "replays")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, time.Now().Format("20060102-150405")+"-"+g.mode.name+".json")
+
+	return path, saveReplay(path, g.replay())
+}
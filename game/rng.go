@@ -0,0 +1,39 @@
+package game
+
+import "math/rand"
+
+// rngSource is a splitmix64 random source
+// Unlike the math/rand source its state is a single number, so it can be
+// captured for replay keyframes and restored exactly.
+type rngSource struct {
+	state uint64
+}
+
+// newRNG returns a random number generator and its source
+func newRNG(seed int64) (*rand.Rand, *rngSource) {
+	src := &rngSource{}
+	src.Seed(seed)
+
+	return rand.New(src), src
+}
+
+// Seed implements rand.Source
+func (s *rngSource) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// Uint64 implements rand.Source64
+func (s *rngSource) Uint64() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+
+	z := s.state
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+
+	return z ^ z>>31
+}
+
+// Int63 implements rand.Source
+func (s *rngSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
@@ -0,0 +1,592 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// saveVersion is the version of the save file schema
+const saveVersion = 2
+
+// saveMigrations upgrade a decoded save file by one version, keyed by the
+// version they upgrade from
+var saveMigrations = map[int]func(save map[string]interface{}) error{
+	// Version 2 added respawning & extra lifes, older games get the default
+	// rules without extra lifes for the score they already have
+	1: func(save map[string]interface{}) error {
+		config, _ := save["config"].(map[string]interface{})
+		pc, _ := config["player"].(map[string]interface{})
+		ps, _ := save["player"].(map[string]interface{})
+		score, _ := save["score"].(float64)
+		if pc == nil || ps == nil {
+			return fmt.Errorf("missing player")
+		}
+
+		d := defaultConfig().pc
+		pc["respawnTicks"] = d.respawnTicks
+		pc["invulnerableTicks"] = d.invulnerableTicks
+		pc["extraLifes"] = d.extraLifes
+		pc["extraLifeEvery"] = d.extraLifeEvery
+
+		n := 0
+		for next := d.extraLifeScore(n); next >= 0 && next <= int(score); next = d.extraLifeScore(n) {
+			n++
+		}
+		ps["extraLifes"] = n
+
+		return nil
+	},
+}
+
+// saveData holds the state of a game in progress
+type saveData struct {
+	Version  int           `json:"version"`
+	Mode     string        `json:"mode"`
+	Seed     int64         `json:"seed"`
+	Date     string        `json:"date,omitempty"` // Daily challenge date
+	Practice bool          `json:"practice,omitempty"`
+	Config   *configData   `json:"config"`
+	RNG      uint64        `json:"rng"` // Random source state
+	Ticks    int           `json:"ticks"`
+	Score    int           `json:"score"`
+	Level    int           `json:"level"`
+	Kills    int           `json:"kills"`
+	Rows     int           `json:"rows"`
+	Inputs   string        `json:"inputs"` // Inputs so far, keeps the replay complete
+	Player   playerState   `json:"player"`
+	PBL      []bulletState `json:"playerBullets"`
+	ABL      []bulletState `json:"alienBullets"`
+	Bunkers  []bunkerState `json:"bunkers,omitempty"`
+	Grid     gridState     `json:"grid"`
+	Boss     *bossState    `json:"boss,omitempty"`
+}
+
+// playerState is the saved state of the player
+type playerState struct {
+	X            int32 `json:"x"`
+	Y            int32 `json:"y"`
+	Lifes        int   `json:"lifes"`
+	Respawn      int   `json:"respawn"`
+	Invulnerable int   `json:"invulnerable"`
+	ExtraLifes   int   `json:"extraLifes"`
+}
+
+// bulletState is the saved state of a bullet
+type bulletState struct {
+	Weapon    string  `json:"weapon,omitempty"` // Projectile name (empty: plain bullet)
+	Speed     int32   `json:"speed,omitempty"`  // Plain bullet config
+	Direction int32   `json:"direction,omitempty"`
+	X         int32   `json:"x"`
+	Y         int32   `json:"y"`
+	W         int32   `json:"w"`
+	H         int32   `json:"h"`
+	FX        float64 `json:"fx"`
+	FY        float64 `json:"fy"`
+	VX        float64 `json:"vx"`
+	OX        float64 `json:"ox"`
+	Age       int     `json:"age"`
+}
+
+// bunkerState is the saved state of a bunker, rows of intact (#) and destroyed
+// (.) cells
+type bunkerState struct {
+	X     int32    `json:"x"`
+	Y     int32    `json:"y"`
+	Cells []string `json:"cells"`
+}
+
+// gridState is the saved state of the alien grid, empty slots are gaps
+type gridState struct {
+	Rows        int          `json:"rows"`
+	Cols        int          `json:"cols"`
+	OriginX     int32        `json:"originX"`
+	OriginY     int32        `json:"originY"`
+	Direction   int32        `json:"direction"`
+	DropCount   int          `json:"dropCount"`
+	Speed       int          `json:"speed"`
+	MoveCounter int          `json:"moveCounter"`
+	Divers      int          `json:"divers"`
+	Aliens      []alienState `json:"aliens"`
+}
+
+// alienState is the saved state of an alien
+type alienState struct {
+	Type  string     `json:"type"`
+	X     int32      `json:"x"`
+	Y     int32      `json:"y"`
+	Row   int        `json:"row"`
+	Col   int        `json:"col"`
+	HomeX int32      `json:"homeX"`
+	HomeY int32      `json:"homeY"`
+	Dive  *diveState `json:"dive,omitempty"`
+}
+
+// diveState is the saved state of a dive
+type diveState struct {
+	Points    [][2]float64 `json:"points"`
+	Ticks     int          `json:"ticks"`
+	Wrap      bool         `json:"wrap"`
+	Tick      int          `json:"tick"`
+	Returning bool         `json:"returning"`
+	From      [2]float64   `json:"from"`
+}
+
+// bossState is the saved state of the boss
+type bossState struct {
+	X         int32 `json:"x"`
+	Y         int32 `json:"y"`
+	BaseY     int32 `json:"baseY"`
+	HP        int   `json:"hp"`
+	MaxHP     int   `json:"maxHP"`
+	Direction int32 `json:"direction"`
+	Ticks     int   `json:"ticks"`
+	Flash     int   `json:"flash"`
+}
+
+// savePath returns the path of the save file
+func (g *Game) savePath() (string, error) {
+	dir, err := dataDir(g.o)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "save.json"), nil
+}
+
+// hasSave checks if there is a game to continue
+func (g *Game) hasSave() bool {
+	path, err := g.savePath()
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(path)
+
+	return err == nil
+}
+
+// saveGame saves the game in progress
+func (g *Game) saveGame() error {
+	path, err := g.savePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(g.save())
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// saveProgress saves the game in progress so it can be continued, games of
+// bots, test plays and games changed with the console aren't saved
+func (g *Game) saveProgress() {
+	if g.o.Controller != nil || g.o.Bot != "" || g.testing || g.cheated {
+		return
+	}
+
+	if err := g.saveGame(); err != nil {
+		slog.Error("couldn't save game", "err", err)
+	}
+}
+
+// removeSave removes the save file
+func (g *Game) removeSave() error {
+	path, err := g.savePath()
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// loadSave loads the save file and upgrades it to the current schema
+func (g *Game) loadSave() (*saveData, error) {
+	path, err := g.savePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("couldn't parse save: %v", err)
+	}
+
+	version, _ := raw["version"].(float64)
+	if int(version) > saveVersion {
+		return nil, fmt.Errorf("save version %d is newer than the supported version %d", int(version), saveVersion)
+	}
+	for v := int(version); v < saveVersion; v++ {
+		migrate, ok := saveMigrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration for save version %d", v)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("couldn't migrate save version %d: %v", v, err)
+		}
+		raw["version"] = float64(v + 1)
+	}
+
+	// Decode the upgraded save through JSON again
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	sv := &saveData{}
+	if err := json.Unmarshal(data, sv); err != nil {
+		return nil, fmt.Errorf("couldn't parse save: %v", err)
+	}
+
+	return sv, nil
+}
+
+// continueGame continues the saved game
+func (g *Game) continueGame() error {
+	sv, err := g.loadSave()
+	if err != nil {
+		return err
+	}
+
+	g.mode, err = findMode(sv.Mode)
+	if err != nil {
+		return err
+	}
+	if sv.Config == nil {
+		return fmt.Errorf("save has no config")
+	}
+
+	g.daily = nil
+	if sv.Date != "" {
+		g.daily, err = newDailyChallenge(sv.Date)
+		if err != nil {
+			return err
+		}
+	}
+
+	g.c = sv.Config.config()
+	g.seed = sv.Seed
+	g.practice = sv.Practice
+	g.resume = sv
+
+	if err := g.switchScene(scenePlay); err != nil {
+		g.resume = nil
+		return err
+	}
+
+	// A game can only be continued once, the save is kept until the game runs
+	if err := g.removeSave(); err != nil {
+		slog.Error("couldn't remove save", "err", err)
+	}
+
+	return nil
+}
+
+// save returns the state of the game in progress
+func (g *Game) save() *saveData {
+	sv := &saveData{
+		Version:  saveVersion,
+		Mode:     g.mode.name,
+		Seed:     g.seed,
+		Practice: g.practice,
+		Config:   g.c.data(),
+		RNG:      g.src.state,
+		Ticks:    g.ticks,
+		Score:    g.score,
+		Level:    g.level,
+		Kills:    g.kills,
+		Rows:     g.rows,
+		Inputs:   string(g.inputs),
+		Player: playerState{
+			X:            g.p.x,
+			Y:            g.p.y,
+			Lifes:        g.p.lifes,
+			Respawn:      g.p.respawn,
+			Invulnerable: g.p.invulnerable,
+			ExtraLifes:   g.p.extraLifes,
+		},
+		PBL: g.saveBullets(*g.pbl),
+		ABL: g.saveBullets(*g.abl),
+		Grid: gridState{
+			Rows:        len(g.ag.alienGridPos),
+			Cols:        g.ag.c.cols,
+			OriginX:     g.ag.originX,
+			OriginY:     g.ag.originY,
+			Direction:   g.ag.direction,
+			DropCount:   g.ag.dropCount,
+			Speed:       g.ag.speed,
+			MoveCounter: g.ag.moveCounter,
+			Divers:      g.ag.divers,
+		},
+	}
+
+	if g.mode.daily && g.daily != nil {
+		sv.Date = g.daily.date
+	}
+
+	for _, b := range *g.bunkers {
+		bs := bunkerState{X: b.x, Y: b.y}
+		for _, row := range b.cells {
+			line := []byte{}
+			for _, intact := range row {
+				if intact {
+					line = append(line, '#')
+				} else {
+					line = append(line, '.')
+				}
+			}
+			bs.Cells = append(bs.Cells, string(line))
+		}
+		sv.Bunkers = append(sv.Bunkers, bs)
+	}
+
+	for _, a := range g.ag.alienList {
+		as := alienState{
+			Type:  alienTypeName(a.typ),
+			X:     a.x,
+			Y:     a.y,
+			Row:   a.row,
+			Col:   a.col,
+			HomeX: a.homeX,
+			HomeY: a.homeY,
+		}
+		if d := a.dive; d != nil {
+			as.Dive = &diveState{
+				Ticks:     d.ticks,
+				Wrap:      d.wrap,
+				Tick:      d.tick,
+				Returning: d.returning,
+				From:      [2]float64{d.from.x, d.from.y},
+			}
+			for _, p := range d.points {
+				as.Dive.Points = append(as.Dive.Points, [2]float64{p.x, p.y})
+			}
+		}
+		sv.Grid.Aliens = append(sv.Grid.Aliens, as)
+	}
+
+	if b := g.boss; b != nil {
+		sv.Boss = &bossState{
+			X:         b.x,
+			Y:         b.y,
+			BaseY:     b.baseY,
+			HP:        b.hp,
+			MaxHP:     b.maxHP,
+			Direction: b.direction,
+			Ticks:     b.ticks,
+			Flash:     b.flash,
+		}
+	}
+
+	return sv
+}
+
+// saveBullets returns the state of a bullet list
+func (g *Game) saveBullets(bl bulletList) []bulletState {
+	bs := []bulletState{}
+	for _, b := range bl {
+		s := bulletState{
+			Weapon: g.weaponName(b.c),
+			X:      b.x,
+			Y:      b.y,
+			W:      b.w,
+			H:      b.h,
+			FX:     b.fx,
+			FY:     b.fy,
+			VX:     b.vx,
+			OX:     b.ox,
+			Age:    b.age,
+		}
+		if s.Weapon == "" {
+			s.Speed = b.c.speed
+			s.Direction = b.c.direction
+		}
+		bs = append(bs, s)
+	}
+
+	return bs
+}
+
+// restoreSave restores the state of a saved game into a freshly started one
+func (g *Game) restoreSave(sv *saveData) error {
+	r := g.a.GetRenderer()
+
+	// Build the saved level, then move everything into place
+	g.level = sv.Level - 1
+	if err := g.startLevel(r); err != nil {
+		return err
+	}
+	if (sv.Boss != nil) != (g.boss != nil) {
+		return fmt.Errorf("saved boss doesn't match level %d", sv.Level)
+	}
+
+	g.src.state = sv.RNG
+	g.ticks = sv.Ticks
+	g.score = sv.Score
+	g.kills = sv.Kills
+	g.rows = sv.Rows
+	g.inputs = append(g.inputs[:0], sv.Inputs...)
+
+	g.p.x, g.p.y, g.p.lifes = sv.Player.X, sv.Player.Y, sv.Player.Lifes
+	g.p.respawn = sv.Player.Respawn
+	g.p.invulnerable = sv.Player.Invulnerable
+	g.p.extraLifes = sv.Player.ExtraLifes
+
+	var err error
+	if *g.pbl, err = g.restoreBullets(sv.PBL); err != nil {
+		return err
+	}
+	if *g.abl, err = g.restoreBullets(sv.ABL); err != nil {
+		return err
+	}
+
+	*g.bunkers = bunkerList{}
+	for _, bs := range sv.Bunkers {
+		b := &bunker{r: r, x: bs.X, y: bs.Y}
+		for row := 0; row < bunkerRows && row < len(bs.Cells); row++ {
+			for col := 0; col < bunkerCols && col < len(bs.Cells[row]); col++ {
+				b.cells[row][col] = bs.Cells[row][col] == '#'
+			}
+		}
+		*g.bunkers = append(*g.bunkers, b)
+	}
+	g.bunkers.index(g.shelter)
+
+	ag := g.ag
+	ag.originX, ag.originY = sv.Grid.OriginX, sv.Grid.OriginY
+	ag.direction = sv.Grid.Direction
+	ag.dropCount = sv.Grid.DropCount
+	ag.speed = sv.Grid.Speed
+	ag.moveCounter = sv.Grid.MoveCounter
+	ag.divers = sv.Grid.Divers
+	ag.alienList = nil
+	ag.alienGridPos = make([][]*alien, sv.Grid.Rows)
+	for row := range ag.alienGridPos {
+		ag.alienGridPos[row] = make([]*alien, sv.Grid.Cols)
+	}
+	for _, as := range sv.Grid.Aliens {
+		typ, ok := alienTypes[as.Type]
+		if !ok {
+			return fmt.Errorf("unknown alien type %s", as.Type)
+		}
+		if as.Row < 0 || as.Row >= sv.Grid.Rows || as.Col < 0 || as.Col >= sv.Grid.Cols {
+			return fmt.Errorf("alien outside of the grid at %d, %d", as.Row, as.Col)
+		}
+
+		a := newAlien(r, ag.s, typ, as.X, as.Y, as.Row, as.Col)
+		a.homeX, a.homeY = as.HomeX, as.HomeY
+		if ds := as.Dive; ds != nil {
+			a.dive = &dive{
+				ticks:     ds.Ticks,
+				wrap:      ds.Wrap,
+				tick:      ds.Tick,
+				returning: ds.Returning,
+				from:      point{x: ds.From[0], y: ds.From[1]},
+			}
+			for _, p := range ds.Points {
+				a.dive.points = append(a.dive.points, point{x: p[0], y: p[1]})
+			}
+		}
+		ag.alienList = append(ag.alienList, a)
+		ag.alienGridPos[as.Row][as.Col] = a
+	}
+	ag.index()
+
+	if bs := sv.Boss; bs != nil {
+		b := g.boss
+		b.x, b.y, b.baseY = bs.X, bs.Y, bs.BaseY
+		b.hp, b.maxHP = bs.HP, bs.MaxHP
+		b.direction = bs.Direction
+		b.ticks = bs.Ticks
+		b.flash = bs.Flash
+	}
+
+	return nil
+}
+
+// restoreBullets returns a bullet list from saved bullets
+func (g *Game) restoreBullets(bs []bulletState) (bulletList, error) {
+	bl := bulletList{}
+	for _, s := range bs {
+		c := g.weapon(s.Weapon)
+		if c == nil && s.Weapon != "" {
+			return nil, fmt.Errorf("unknown projectile %s", s.Weapon)
+		}
+		if c == nil {
+			// Plain bullets going up are the player's
+			c = &bulletConfig{
+				speed:     s.Speed,
+				direction: s.Direction,
+				color:     roleEnemyBullet,
+			}
+			if s.Direction < 0 {
+				c.color = rolePlayerBullet
+			}
+		}
+
+		b := makeBullet(g.a.GetRenderer(), s.X, s.Y, c)
+		b.w, b.h = s.W, s.H
+		b.fx, b.fy = s.FX, s.FY
+		b.vx, b.ox = s.VX, s.OX
+		b.age = s.Age
+		bl = append(bl, b)
+	}
+
+	return bl, nil
+}
+
+// weaponName returns the projectile name of a bullet config of the alien grid
+// or the boss, or an empty string for plain bullets
+func (g *Game) weaponName(c *bulletConfig) string {
+	for name, w := range g.ag.weapons {
+		if w == c {
+			return name
+		}
+	}
+
+	if g.boss != nil {
+		for name, w := range g.boss.weapons {
+			if w == c {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// weapon returns the bullet config of a projectile of the alien grid or the
+// boss
+func (g *Game) weapon(name string) *bulletConfig {
+	if c, ok := g.ag.weapons[name]; ok {
+		return c
+	}
+
+	if g.boss != nil {
+		if c, ok := g.boss.weapons[name]; ok {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// alienTypeName returns the name of an alien type
+func alienTypeName(typ *alienType) string {
+	for name, t := range alienTypes {
+		if t == typ {
+			return name
+		}
+	}
+
+	return ""
+}
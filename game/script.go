@@ -0,0 +1,434 @@
+package game
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// scriptTimeout is how long a single hook call may run
+const scriptTimeout = 20 * time.Millisecond
+
+// scripts runs the Lua mod scripts of a game
+// Scripts define global hook functions which are called by the game:
+//
+//	onMove(alien)          after the grid moved, may change alien.x & alien.y
+//	onFire(alien, weapon)  returns the projectile to fire, false holds fire
+//	onHit(alien)           an alien has been shot
+//	onWave(level)          returns the rows of a level grid, nil generates it
+//	onScore(event, points) returns the points for "alien" & "boss" hits
+//
+// The global game table is a copy of the game state. Changes to player.lifes,
+// grid.direction & grid.speed are applied after each tick. Scripts run without
+// the io, os & package libraries and math.random draws from the game's random
+// number generator, so scripted games stay deterministic.
+type scripts struct {
+	dir      string            // Watched directory (empty: fixed sources)
+	sources  map[string]string // Code by file name
+	modTimes map[string]time.Time
+	rng      *rand.Rand
+	L        *lua.LState
+	err      string // Last error, shown in the HUD
+}
+
+// loadScripts loads all scripts of a directory
+func loadScripts(dir string, rng *rand.Rand) (*scripts, error) {
+	s := &scripts{dir: dir, rng: rng}
+
+	sources, modTimes, err := readScripts(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.load(sources); err != nil {
+		return nil, err
+	}
+	s.modTimes = modTimes
+
+	return s, nil
+}
+
+// newScripts returns scripts for fixed sources
+func newScripts(sources map[string]string, rng *rand.Rand) (*scripts, error) {
+	s := &scripts{rng: rng}
+
+	return s, s.load(sources)
+}
+
+// readScripts reads all Lua files of a directory
+func readScripts(dir string) (map[string]string, map[string]time.Time, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := map[string]string{}
+	modTimes := map[string]time.Time{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		code, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sources[filepath.Base(path)] = string(code)
+		modTimes[filepath.Base(path)] = info.ModTime()
+	}
+
+	return sources, modTimes, nil
+}
+
+// load runs the sources in a fresh sandbox, the running scripts are only
+// replaced if all of them load
+func (s *scripts) load(sources map[string]string) error {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// No file access or loading of code from strings
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "collectgarbage", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	L.SetGlobal("print", L.NewFunction(scriptPrint))
+	math := L.GetGlobal("math").(*lua.LTable)
+	L.SetField(math, "random", L.NewFunction(s.random))
+	L.SetField(math, "randomseed", L.NewFunction(func(L *lua.LState) int { return 0 }))
+	L.SetGlobal("game", L.NewTable())
+
+	names := []string{}
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fn, err := L.Load(strings.NewReader(sources[name]), name)
+		if err == nil {
+			L.Push(fn)
+			err = s.protect(L, func() error { return L.PCall(0, 0, nil) })
+		}
+		if err != nil {
+			L.Close()
+			return fmt.Errorf("couldn't load script %s: %v", name, err)
+		}
+	}
+
+	if s.L != nil {
+		s.L.Close()
+	}
+	s.L = L
+	s.sources = sources
+	s.err = ""
+
+	return nil
+}
+
+// Close closes the Lua state
+func (s *scripts) Close() {
+	if s.L != nil {
+		s.L.Close()
+		s.L = nil
+	}
+}
+
+// reload reloads the scripts if a file has changed and returns true if it
+// did, errors keep the running scripts
+func (s *scripts) reload() bool {
+	if s == nil || s.dir == "" {
+		return false
+	}
+
+	sources, modTimes, err := readScripts(s.dir)
+	if err != nil {
+		s.fail("reload", err)
+		return false
+	}
+
+	changed := len(modTimes) != len(s.modTimes)
+	for name, t := range modTimes {
+		if !t.Equal(s.modTimes[name]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false
+	}
+	s.modTimes = modTimes
+
+	if err := s.load(sources); err != nil {
+		s.fail("reload", err)
+		return false
+	}
+	slog.Info("reloaded scripts", "dir", s.dir, "scripts", len(sources))
+
+	return true
+}
+
+// protect runs f with the time limit of a hook call
+func (s *scripts) protect(L *lua.LState, f func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), scriptTimeout)
+	defer cancel()
+
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	return f()
+}
+
+// call calls a hook and returns its results, missing hooks and errors return
+// nil
+func (s *scripts) call(hook string, nret int, args ...lua.LValue) []lua.LValue {
+	fn, ok := s.L.GetGlobal(hook).(*lua.LFunction)
+	if !ok {
+		return nil
+	}
+
+	err := s.protect(s.L, func() error {
+		return s.L.CallByParam(lua.P{Fn: fn, NRet: nret, Protect: true}, args...)
+	})
+	if err != nil {
+		s.fail(hook, err)
+		return nil
+	}
+
+	ret := make([]lua.LValue, nret)
+	for i := range ret {
+		ret[i] = s.L.Get(-nret + i)
+	}
+	s.L.Pop(nret)
+
+	return ret
+}
+
+// fail reports a script error
+func (s *scripts) fail(hook string, err error) {
+	msg := fmt.Sprintf("%s: %v", hook, err)
+	if msg != s.err {
+		slog.Warn("script error", "hook", hook, "err", err)
+	}
+	s.err = msg
+}
+
+// random implements math.random with the game's random number generator
+func (s *scripts) random(L *lua.LState) int {
+	switch L.GetTop() {
+	case 0:
+		L.Push(lua.LNumber(s.rng.Float64()))
+	case 1:
+		n := L.CheckInt(1)
+		if n < 1 {
+			L.ArgError(1, "interval is empty")
+		}
+		L.Push(lua.LNumber(s.rng.Intn(n) + 1))
+	default:
+		m, n := L.CheckInt(1), L.CheckInt(2)
+		if n < m {
+			L.ArgError(2, "interval is empty")
+		}
+		L.Push(lua.LNumber(m + s.rng.Intn(n-m+1)))
+	}
+
+	return 1
+}
+
+// scriptPrint prints script output to the console
+func scriptPrint(L *lua.LState) int {
+	args := []string{}
+	for i := 1; i <= L.GetTop(); i++ {
+		args = append(args, L.ToStringMeta(L.Get(i)).String())
+	}
+	slog.Info("script", "msg", strings.Join(args, " "))
+
+	return 0
+}
+
+// alienTable returns the script view of an alien
+func (s *scripts) alienTable(a *alien) *lua.LTable {
+	t := s.L.NewTable()
+	s.L.SetField(t, "x", lua.LNumber(a.x))
+	s.L.SetField(t, "y", lua.LNumber(a.y))
+	s.L.SetField(t, "row", lua.LNumber(a.row))
+	s.L.SetField(t, "col", lua.LNumber(a.col))
+	s.L.SetField(t, "type", lua.LString(alienTypeName(a.typ)))
+	s.L.SetField(t, "diving", lua.LBool(a.dive != nil))
+
+	return t
+}
+
+// onMove lets scripts move an alien
+func (s *scripts) onMove(a *alien) {
+	if s == nil {
+		return
+	}
+
+	t := s.alienTable(a)
+	if s.call("onMove", 0, t) == nil {
+		return
+	}
+
+	maxX, maxY := screenSize(a.r)
+	if x, ok := s.L.GetField(t, "x").(lua.LNumber); ok {
+		a.x = max(-a.w, min(int32(x), int32(maxX)))
+	}
+	if y, ok := s.L.GetField(t, "y").(lua.LNumber); ok {
+		a.y = max(-a.h, min(int32(y), int32(maxY)))
+	}
+}
+
+// onFire lets scripts pick the weapon of an alien, false holds fire
+func (s *scripts) onFire(a *alien, weapon string) (string, bool) {
+	if s == nil {
+		return weapon, true
+	}
+
+	ret := s.call("onFire", 1, s.alienTable(a), lua.LString(weapon))
+	if ret == nil || ret[0] == lua.LNil {
+		return weapon, true
+	}
+	if ret[0] == lua.LFalse {
+		return weapon, false
+	}
+	if name, ok := ret[0].(lua.LString); ok {
+		return string(name), true
+	}
+
+	return weapon, true
+}
+
+// onHit tells scripts that an alien has been shot
+func (s *scripts) onHit(a *alien) {
+	if s == nil {
+		return
+	}
+
+	s.call("onHit", 0, s.alienTable(a))
+}
+
+// onWave lets scripts lay out a level, it returns nil if they don't
+func (s *scripts) onWave(n int) *level {
+	if s == nil {
+		return nil
+	}
+
+	ret := s.call("onWave", 2, lua.LNumber(n))
+	if ret == nil {
+		return nil
+	}
+	rows, ok := ret[0].(*lua.LTable)
+	if !ok {
+		return nil
+	}
+
+	l := &level{}
+	rows.ForEach(func(_, v lua.LValue) {
+		l.Grid = append(l.Grid, lua.LVAsString(v))
+	})
+	if n, ok := ret[1].(lua.LNumber); ok {
+		l.Bunkers = int(n)
+	}
+
+	if err := l.validate(); err != nil {
+		s.fail("onWave", err)
+		return nil
+	}
+
+	return l
+}
+
+// onScore lets scripts change the points for an event
+func (s *scripts) onScore(event string, points int) int {
+	if s == nil {
+		return points
+	}
+
+	ret := s.call("onScore", 1, lua.LString(event), lua.LNumber(points))
+	if ret == nil {
+		return points
+	}
+	if n, ok := ret[0].(lua.LNumber); ok {
+		return int(n)
+	}
+
+	return points
+}
+
+// push copies the game state into the game table
+func (s *scripts) push(g *Game) {
+	if s == nil {
+		return
+	}
+
+	maxX, maxY := screenSize(g.a.GetRenderer())
+	t := s.L.NewTable()
+	s.L.SetField(t, "tick", lua.LNumber(g.ticks))
+	s.L.SetField(t, "level", lua.LNumber(g.level))
+	s.L.SetField(t, "score", lua.LNumber(g.score))
+	s.L.SetField(t, "width", lua.LNumber(maxX))
+	s.L.SetField(t, "height", lua.LNumber(maxY))
+
+	p := s.L.NewTable()
+	s.L.SetField(p, "x", lua.LNumber(g.p.x))
+	s.L.SetField(p, "y", lua.LNumber(g.p.y))
+	s.L.SetField(p, "lifes", lua.LNumber(g.p.lifes))
+	s.L.SetField(t, "player", p)
+
+	grid := s.L.NewTable()
+	s.L.SetField(grid, "direction", lua.LNumber(g.ag.direction))
+	s.L.SetField(grid, "speed", lua.LNumber(g.ag.speed))
+	s.L.SetField(grid, "dropCount", lua.LNumber(g.ag.dropCount))
+	s.L.SetField(grid, "aliens", lua.LNumber(len(g.ag.alienList)))
+	s.L.SetField(t, "grid", grid)
+
+	s.L.SetGlobal("game", t)
+}
+
+// pull applies the writable fields of the game table to the game
+func (s *scripts) pull(g *Game) {
+	if s == nil {
+		return
+	}
+
+	t, ok := s.L.GetGlobal("game").(*lua.LTable)
+	if !ok {
+		return
+	}
+
+	if p, ok := s.L.GetField(t, "player").(*lua.LTable); ok {
+		if lifes, ok := s.L.GetField(p, "lifes").(lua.LNumber); ok && lifes > 0 {
+			g.p.lifes = min(int(lifes), 99)
+		}
+	}
+
+	if grid, ok := s.L.GetField(t, "grid").(*lua.LTable); ok {
+		if d, ok := s.L.GetField(grid, "direction").(lua.LNumber); ok && (d == 1 || d == -1) {
+			g.ag.direction = int32(d)
+		}
+		if speed, ok := s.L.GetField(grid, "speed").(lua.LNumber); ok && speed >= 1 {
+			g.ag.speed = min(int(speed), g.ag.c.speedMax)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/MichaelThessel/spacee/app"
+	"github.com/veandco/go-sdl2/sdl"
+	mix "github.com/veandco/go-sdl2/sdl_mixer"
+)
+
+// Actions that can be bound to keys
+const (
+	actionLeft  = "left"
+	actionRight = "right"
+	actionFire  = "fire"
+	actionPause = "pause"
+)
+
+// settings holds the video, audio, gameplay & control options, they are kept
+// in the settings object of the config file
+type settings struct {
+	Width      int               `json:"width"`
+	Height     int               `json:"height"`
+	Fullscreen bool              `json:"fullscreen"`
+	VSync      bool              `json:"vsync"`    // Applies on the next start
+	FrameCap   int               `json:"frameCap"` // Rendered frames per second (0: one per tick)
+	Volume     int               `json:"volume"`   // Master volume in percent
+	Effects    int               `json:"effects"`  // Sound effect volume in percent
+	Difficulty string            `json:"difficulty"`
+	Custom     difficultyData    `json:"custom"` // Values of the custom difficulty
+	Assists    assists           `json:"assists"`
+	Theme      string            `json:"theme"` // Built-in theme or theme file
+	Keys       map[string]string `json:"keys"`  // Key names by action
+}
+
+// defaultSettings returns the settings of a new player
+func defaultSettings() *settings {
+	return &settings{
+		Width:      1200,
+		Height:     800,
+		Volume:     100,
+		Effects:    100,
+		Difficulty: difficultyNormal,
+		Custom:     difficultyData{FireRate: 1, BulletSpeed: 1, StepSizeY: 1},
+		Theme:      themes[0].name,
+		Keys: map[string]string{
+			actionLeft:  sdl.GetKeyName(sdl.K_LEFT),
+			actionRight: sdl.GetKeyName(sdl.K_RIGHT),
+			actionFire:  sdl.GetKeyName(sdl.K_SPACE),
+			actionPause: sdl.GetKeyName(sdl.K_p),
+		},
+	}
+}
+
+// settingsPath returns the config file the settings are kept in, the data
+// dir has one unless a config file is set
+func settingsPath(o *Options) (string, error) {
+	if o.Config != "" {
+		return o.Config, nil
+	}
+
+	dir, err := dataDir(o)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// loadSettings loads the settings from the config file, missing values keep
+// their defaults and a missing file is no error
+func loadSettings(o *Options) (*settings, error) {
+	s := defaultSettings()
+
+	path, err := settingsPath(o)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load settings: %v", err)
+	}
+
+	file := struct {
+		Settings *settings `json:"settings"`
+	}{s}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("couldn't parse settings %s: %v", path, err)
+	}
+
+	// Actions missing from the file keep their default keys
+	for action, key := range defaultSettings().Keys {
+		if _, ok := s.Keys[action]; !ok {
+			s.Keys[action] = key
+		}
+	}
+
+	if _, err := findDifficulty(s.Difficulty); err != nil {
+		return nil, fmt.Errorf("invalid settings %s: %v", path, err)
+	}
+	if err := s.Custom.validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings %s: %v", path, err)
+	}
+
+	return s, nil
+}
+
+// saveSettings writes the settings into the config file, the rest of the file
+// is kept
+func saveSettings(o *Options, s *settings) error {
+	path, err := settingsPath(o)
+	if err != nil {
+		return err
+	}
+
+	file := map[string]json.RawMessage{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("couldn't parse config %s: %v", path, err)
+		}
+	}
+
+	if file["settings"], err = json.Marshal(s); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// key returns the key bound to an action
+func (s *settings) key(action string) sdl.Keycode {
+	return sdl.GetKeyFromName(s.Keys[action])
+}
+
+// ConfigureApp applies the settings the app has to be created with
+func ConfigureApp(o *Options, c *app.Config) {
+	s, err := loadSettings(o)
+	if err != nil {
+		slog.Warn("couldn't load settings", "err", err)
+		return
+	}
+
+	c.VSync = s.VSync
+}
+
+// applySettings applies the video & audio settings to the app
+func (g *Game) applySettings() {
+	s := g.settings
+
+	if err := g.a.SetWindowMode(s.Width, s.Height, s.Fullscreen); err != nil {
+		slog.Error("couldn't apply video settings", "err", err)
+	}
+	g.a.SetFrameCap(s.FrameCap)
+	mix.Volume(-1, mix.MAX_VOLUME*s.Volume*s.Effects/10000)
+
+	theme := s.Theme
+	if g.theme != "" {
+		theme = g.theme
+	}
+	if theme != activeTheme.name {
+		if err := useTheme(theme); err != nil {
+			slog.Error("couldn't apply theme", "err", err)
+		}
+	}
+	g.a.SetBackground(themeColor(roleBackground))
+}
+
+// changeSettings applies & saves changed settings
+func (g *Game) changeSettings() {
+	g.applySettings()
+
+	if err := saveSettings(g.o, g.settings); err != nil {
+		slog.Error("couldn't save settings", "err", err)
+	}
+}
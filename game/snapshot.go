@@ -0,0 +1,118 @@
+package game
+
+import "github.com/MichaelThessel/spacee/collision"
+
+// snapshot holds a copy of the simulation state at a tick
+// Textures, sounds and configs are shared with the live game, everything that
+// changes while playing is copied.
+type snapshot struct {
+	ticks int
+	score int
+	level int
+	kills int
+	rows  int
+	rng   uint64
+	p     player
+	pbl   bulletList
+	abl   bulletList
+	bl    bunkerList
+	ag    alienGrid
+	boss  *boss
+}
+
+// snapshot captures the current simulation state
+func (g *Game) snapshot() *snapshot {
+	s := &snapshot{
+		ticks: g.ticks,
+		score: g.score,
+		level: g.level,
+		kills: g.kills,
+		rows:  g.rows,
+		rng:   g.src.state,
+		p:     *g.p,
+		pbl:   copyBullets(*g.pbl),
+		abl:   copyBullets(*g.abl),
+		bl:    copyBunkers(*g.bunkers),
+		ag:    copyGrid(g.ag),
+	}
+
+	if g.boss != nil {
+		b := *g.boss
+		s.boss = &b
+	}
+
+	return s
+}
+
+// restore resets the simulation to a snapshot
+// The snapshot is copied again, so it can be restored any number of times.
+func (g *Game) restore(s *snapshot) {
+	g.ticks = s.ticks
+	g.score = s.score
+	g.level = s.level
+	g.kills = s.kills
+	g.rows = s.rows
+	g.src.state = s.rng
+
+	// Render callbacks are bound to these, so they are updated in place
+	*g.p = s.p
+	*g.pbl = copyBullets(s.pbl)
+	*g.abl = copyBullets(s.abl)
+	*g.bunkers = copyBunkers(s.bl)
+	g.bunkers.index(g.shelter)
+	*g.ag = copyGrid(&s.ag)
+
+	g.boss = nil
+	if s.boss != nil {
+		b := *s.boss
+		g.boss = &b
+	}
+
+	if len(g.inputs) > g.ticks {
+		g.inputs = g.inputs[:g.ticks]
+	}
+}
+
+// copyBullets returns a copy of a bullet list
+func copyBullets(bl bulletList) bulletList {
+	c := make(bulletList, len(bl))
+	for i, b := range bl {
+		cb := *b
+		c[i] = &cb
+	}
+
+	return c
+}
+
+// copyGrid returns a copy of an alien grid
+func copyGrid(ag *alienGrid) alienGrid {
+	c := *ag
+	c.broad = collision.NewGrid(128)
+
+	aliens := make(map[*alien]*alien, len(ag.alienList))
+	c.alienList = make([]*alien, len(ag.alienList))
+	for i, a := range ag.alienList {
+		ca := *a
+		if a.dive != nil {
+			d := *a.dive
+			ca.dive = &d
+		}
+		aliens[a] = &ca
+		c.alienList[i] = &ca
+	}
+
+	c.alienGridPos = make([][]*alien, len(ag.alienGridPos))
+	for row := range ag.alienGridPos {
+		c.alienGridPos[row] = make([]*alien, len(ag.alienGridPos[row]))
+		for col, a := range ag.alienGridPos[row] {
+			if a != nil {
+				c.alienGridPos[row][col] = aliens[a]
+			}
+		}
+	}
+
+	c.byID = nil
+	c.index()
+
+	return c
+}
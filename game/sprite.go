@@ -0,0 +1,70 @@
+package game
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/MichaelThessel/spacee/collision"
+	"github.com/veandco/go-sdl2/sdl"
+	img "github.com/veandco/go-sdl2/sdl_image"
+)
+
+// maskThreshold is the alpha value from which a sprite pixel is solid
+const maskThreshold = 0x80
+
+// sprite holds a texture and its collision mask
+type sprite struct {
+	t *sdl.Texture
+	m *collision.Mask
+}
+
+// loadSprite loads an image as sprite, sprites of the same file are shared
+func loadSprite(r *sdl.Renderer, path string) (*sprite, error) {
+	ac := assetsFor(r)
+	path = filepath.Clean(path)
+
+	if sp, ok := ac.sprites[path]; ok {
+		return sp, nil
+	}
+
+	sp, err := readSprite(r, path)
+	if err != nil {
+		return nil, err
+	}
+	ac.sprites[path] = sp
+
+	return sp, nil
+}
+
+// readSprite loads an image as texture and builds its collision mask from the
+// alpha channel
+func readSprite(r *sdl.Renderer, path string) (*sprite, error) {
+	s, err := img.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load %s: %v", path, err)
+	}
+	defer s.Free()
+
+	// Read the pixels as R, G, B, A bytes regardless of the image format
+	rgba, err := s.ConvertFormat(sdl.PIXELFORMAT_ABGR8888, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't convert %s: %v", path, err)
+	}
+	defer rgba.Free()
+
+	sp := &sprite{}
+	sp.t, err = r.CreateTextureFromSurface(rgba)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create texture for %s: %v", path, err)
+	}
+	liveResources.Add(1)
+
+	rgba.Lock()
+	pixels := rgba.Pixels()
+	sp.m = collision.NewMask(rgba.W, rgba.H, func(x, y int32) uint8 {
+		return pixels[y*rgba.Pitch+x*4+3]
+	}, maskThreshold)
+	rgba.Unlock()
+
+	return sp, nil
+}
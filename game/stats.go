@@ -10,6 +10,7 @@ import (
 type stats struct {
 	r      *sdl.Renderer
 	font   *ttf.Font
+	small  *ttf.Font // Font of messages
 	lifes  int
 	points int
 }
@@ -22,37 +23,70 @@ func newStats(r *sdl.Renderer, lifes int) (*stats, error) {
 	}
 
 	var err error
-	s.font, err = ttf.OpenFont("assets/font.ttf", 40)
+	s.font, err = openFont(40)
 	if err != nil {
-		return nil, fmt.Errorf("could not load font: %v", err)
+		return nil, err
+	}
+
+	s.small, err = openFont(16)
+	if err != nil {
+		s.Close()
+		return nil, err
 	}
 
 	return s, nil
 }
 
+// Close closes the fonts
+func (s *stats) Close() {
+	closeFont(s.font)
+	closeFont(s.small)
+}
+
 // Draw draws the stats
 func (s *stats) Draw(lifes, points int) {
-	maxX, _, _ := s.r.GetRendererOutputSize()
+	maxX, _ := screenSize(s.r)
+	c := themeColor(roleHUDText)
 
-	lsf, _ := s.font.RenderUTF8_Solid(
-		fmt.Sprintf("LIFES: %d", lifes),
-		sdl.Color{R: 0xF6, G: 0x25, B: 0x9B, A: 0},
-	)
-	defer lsf.Free()
+	drawTextLeft(s.r, s.font, fmt.Sprintf("LIFES: %d", lifes), 10, 10, c)
+	drawTextRight(s.r, s.font, fmt.Sprintf("POINTS: %08d", points), int32(maxX)-10, 10, c)
+}
 
-	psf, _ := s.font.RenderUTF8_Solid(
-		fmt.Sprintf("POINTS: %08d", points),
-		sdl.Color{R: 0xF6, G: 0x25, B: 0x9B, A: 0},
+// DrawHealth draws a health bar below the stats
+func (s *stats) DrawHealth(hp, maxHP int) {
+	maxX, _ := screenSize(s.r)
+
+	w, h := int32(400), int32(16)
+	x, y := int32(maxX)/2-w/2, int32(24)
+
+	c := themeColor(roleHUDText)
+	s.r.SetDrawColor(c.R, c.G, c.B, 0xFF)
+	s.r.DrawRect(&sdl.Rect{X: x, Y: y, W: w, H: h})
+	s.r.FillRect(&sdl.Rect{X: x + 2, Y: y + 2, W: (w - 4) * int32(hp) / int32(maxHP), H: h - 4})
+}
+
+// DrawTimer draws the time left below the stats
+func (s *stats) DrawTimer(seconds int) {
+	maxX, _ := screenSize(s.r)
+
+	drawText(
+		s.r,
+		s.font,
+		clock(max(seconds, 0)),
+		int32(maxX)/2,
+		50,
+		themeColor(roleHUDText),
 	)
-	defer psf.Free()
+}
 
-	var clipRect sdl.Rect
-	lsf.GetClipRect(&clipRect)
-	psf.GetClipRect(&clipRect)
+// DrawError draws an error message at the bottom of the screen
+func (s *stats) DrawError(msg string) {
+	maxX, maxY := screenSize(s.r)
 
-	lsfTex, _ := s.r.CreateTextureFromSurface(lsf)
-	psfTex, _ := s.r.CreateTextureFromSurface(psf)
+	// Keep the message on screen
+	if len(msg) > 100 {
+		msg = msg[:97] + "..."
+	}
 
-	s.r.Copy(lsfTex, nil, &sdl.Rect{X: 10, Y: 10, W: lsf.W, H: lsf.H})
-	s.r.Copy(psfTex, nil, &sdl.Rect{X: int32(maxX) - psf.W - 10, Y: 10, W: psf.W, H: psf.H})
+	drawText(s.r, s.small, msg, int32(maxX)/2, int32(maxY)-20, themeColor(roleWarning))
 }
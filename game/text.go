@@ -0,0 +1,73 @@
+package game
+
+import (
+	"log/slog"
+
+	"github.com/veandco/go-sdl2/sdl"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// Text alignments relative to the x position
+const (
+	alignLeft = iota
+	alignCenter
+	alignRight
+)
+
+// drawText draws a line of text horizontally centered on x
+func drawText(r *sdl.Renderer, font *ttf.Font, text string, x, y int32, c sdl.Color) {
+	drawTextAligned(r, font, text, x, y, c, alignCenter)
+}
+
+// drawTextLeft draws a line of text starting at x
+func drawTextLeft(r *sdl.Renderer, font *ttf.Font, text string, x, y int32, c sdl.Color) {
+	drawTextAligned(r, font, text, x, y, c, alignLeft)
+}
+
+// drawTextRight draws a line of text ending at x
+func drawTextRight(r *sdl.Renderer, font *ttf.Font, text string, x, y int32, c sdl.Color) {
+	drawTextAligned(r, font, text, x, y, c, alignRight)
+}
+
+// drawTextAligned draws a line of text, a failure only costs the text of one
+// frame so it is logged at debug level
+func drawTextAligned(r *sdl.Renderer, font *ttf.Font, text string, x, y int32, c sdl.Color, align int) {
+	if text == "" {
+		return
+	}
+
+	s, err := font.RenderUTF8_Solid(text, c)
+	if err != nil {
+		slog.Debug("couldn't render text", "text", text, "err", err)
+		return
+	}
+	defer s.Free()
+
+	t, err := r.CreateTextureFromSurface(s)
+	if err != nil {
+		slog.Debug("couldn't create text texture", "text", text, "err", err)
+		return
+	}
+	defer t.Destroy()
+
+	switch align {
+	case alignCenter:
+		x -= s.W / 2
+	case alignRight:
+		x -= s.W
+	}
+
+	r.Copy(t, nil, &sdl.Rect{X: x, Y: y, W: s.W, H: s.H})
+}
+
+// screenSize returns the size the game is laid out in, the app scales it to
+// the window
+func screenSize(r *sdl.Renderer) (int, int) {
+	if w, h := r.GetLogicalSize(); w > 0 && h > 0 {
+		return int(w), int(h)
+	}
+
+	w, h, _ := r.GetRendererOutputSize()
+
+	return w, h
+}
@@ -0,0 +1,225 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Color roles of a theme
+const (
+	roleHUDText      = "hudText"
+	rolePlayerBullet = "playerBullet" // Player bullets & debris
+	roleEnemyBullet  = "enemyBullet"
+	roleZigzagBullet = "zigzagBullet"
+	roleHomingBullet = "homingBullet"
+	roleBunker       = "bunker"
+	roleWeakPoint    = "weakPoint" // Weak points of the boss
+	roleWarning      = "warning"
+	roleBackground   = "background"
+	roleGrunt        = "grunt" // Alien tints
+	roleZigzagger    = "zigzagger"
+	roleHunter       = "hunter"
+	roleSplitter     = "splitter"
+	roleEditorGrid   = "editorGrid"  // Empty slots of the level editor
+	roleDebug        = "debug"       // Hitboxes & text of the debug overlay & console
+	roleDebugBounds  = "debugBounds" // Grid bounds of the debug overlay
+	roleConsole      = "console"     // Background of the debug console
+)
+
+// defaultFont is the font of themes that don't pick one
+const defaultFont = "assets/font.ttf"
+
+// theme holds the colors by role & the font the game is drawn with
+type theme struct {
+	name   string
+	title  string
+	font   string
+	colors map[string]sdl.Color
+}
+
+// themeData is the serialized form of a theme, colors are #RRGGBB strings
+type themeData struct {
+	Title  string            `json:"title"`
+	Font   string            `json:"font"`   // Font file (empty: the default font)
+	Colors map[string]string `json:"colors"` // Colors by role, missing roles keep the default colors
+}
+
+// themes holds the built-in themes, the first is the default
+var themes = []*theme{
+	{
+		name:  "default",
+		title: "DEFAULT",
+		font:  defaultFont,
+		colors: map[string]sdl.Color{
+			roleHUDText:      {R: 0xF6, G: 0x25, B: 0x9B, A: 0xFF},
+			rolePlayerBullet: {R: 0x00, G: 0xFC, B: 0xFF, A: 0xFF},
+			roleEnemyBullet:  {R: 0xF6, G: 0x25, B: 0x9B, A: 0xFF},
+			roleZigzagBullet: {R: 0xF6, G: 0xE0, B: 0x25, A: 0xFF},
+			roleHomingBullet: {R: 0xFF, G: 0x8C, B: 0x00, A: 0xFF},
+			roleBunker:       {R: 0x25, G: 0xF6, B: 0x6B, A: 0xFF},
+			roleWeakPoint:    {R: 0xF6, G: 0x25, B: 0x9B, A: 0xFF},
+			roleWarning:      {R: 0xFF, G: 0x40, B: 0x40, A: 0xFF},
+			roleBackground:   {R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+			roleGrunt:        {R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleZigzagger:    {R: 0xFF, G: 0xF0, B: 0x80, A: 0xFF},
+			roleHunter:       {R: 0xFF, G: 0xA0, B: 0x60, A: 0xFF},
+			roleSplitter:     {R: 0x90, G: 0xFF, B: 0x90, A: 0xFF},
+			roleEditorGrid:   {R: 0x40, G: 0x40, B: 0x40, A: 0xFF},
+			roleDebug:        {R: 0x40, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleDebugBounds:  {R: 0xFF, G: 0xFF, B: 0x40, A: 0xFF},
+			roleConsole:      {R: 0x10, G: 0x10, B: 0x10, A: 0xFF},
+		},
+	},
+	{
+		// Okabe & Ito palette, the colors stay apart with any color vision
+		name:  "colorblind",
+		title: "COLORBLIND",
+		font:  defaultFont,
+		colors: map[string]sdl.Color{
+			roleHUDText:      {R: 0xE6, G: 0x9F, B: 0x00, A: 0xFF},
+			rolePlayerBullet: {R: 0x56, G: 0xB4, B: 0xE9, A: 0xFF},
+			roleEnemyBullet:  {R: 0xD5, G: 0x5E, B: 0x00, A: 0xFF},
+			roleZigzagBullet: {R: 0xF0, G: 0xE4, B: 0x42, A: 0xFF},
+			roleHomingBullet: {R: 0xCC, G: 0x79, B: 0xA7, A: 0xFF},
+			roleBunker:       {R: 0x00, G: 0x9E, B: 0x73, A: 0xFF},
+			roleWeakPoint:    {R: 0xD5, G: 0x5E, B: 0x00, A: 0xFF},
+			roleWarning:      {R: 0xD5, G: 0x5E, B: 0x00, A: 0xFF},
+			roleBackground:   {R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+			roleGrunt:        {R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleZigzagger:    {R: 0xF0, G: 0xE4, B: 0x42, A: 0xFF},
+			roleHunter:       {R: 0xE6, G: 0x9F, B: 0x00, A: 0xFF},
+			roleSplitter:     {R: 0x56, G: 0xB4, B: 0xE9, A: 0xFF},
+			roleEditorGrid:   {R: 0x50, G: 0x50, B: 0x50, A: 0xFF},
+			roleDebug:        {R: 0x56, G: 0xB4, B: 0xE9, A: 0xFF},
+			roleDebugBounds:  {R: 0xF0, G: 0xE4, B: 0x42, A: 0xFF},
+			roleConsole:      {R: 0x10, G: 0x10, B: 0x10, A: 0xFF},
+		},
+	},
+	{
+		name:  "high-contrast",
+		title: "HIGH CONTRAST",
+		font:  defaultFont,
+		colors: map[string]sdl.Color{
+			roleHUDText:      {R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+			rolePlayerBullet: {R: 0x00, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleEnemyBullet:  {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+			roleZigzagBullet: {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+			roleHomingBullet: {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+			roleBunker:       {R: 0x00, G: 0xFF, B: 0x00, A: 0xFF},
+			roleWeakPoint:    {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+			roleWarning:      {R: 0xFF, G: 0x00, B: 0x00, A: 0xFF},
+			roleBackground:   {R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+			roleGrunt:        {R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleZigzagger:    {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+			roleHunter:       {R: 0xFF, G: 0x00, B: 0xFF, A: 0xFF},
+			roleSplitter:     {R: 0x00, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleEditorGrid:   {R: 0x80, G: 0x80, B: 0x80, A: 0xFF},
+			roleDebug:        {R: 0x00, G: 0xFF, B: 0xFF, A: 0xFF},
+			roleDebugBounds:  {R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF},
+			roleConsole:      {R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+		},
+	},
+}
+
+// activeTheme is the theme the game is drawn with
+var activeTheme = themes[0]
+
+// themeColor returns the color of a role in the active theme
+func themeColor(role string) sdl.Color {
+	return activeTheme.colors[role]
+}
+
+// loadTheme returns the built-in theme of a name or loads a theme file
+func loadTheme(name string) (*theme, error) {
+	if name == "" {
+		return themes[0], nil
+	}
+	for _, t := range themes {
+		if t.name == name {
+			return t, nil
+		}
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load theme: %v", err)
+	}
+
+	var td themeData
+	if err := json.Unmarshal(data, &td); err != nil {
+		return nil, fmt.Errorf("couldn't parse theme %s: %v", name, err)
+	}
+
+	t, err := td.theme(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid theme %s: %v", name, err)
+	}
+
+	return t, nil
+}
+
+// theme returns the theme the data describes
+func (td *themeData) theme(name string) (*theme, error) {
+	t := &theme{
+		name:   name,
+		title:  strings.ToUpper(td.Title),
+		font:   td.Font,
+		colors: map[string]sdl.Color{},
+	}
+	if t.title == "" {
+		t.title = "FILE"
+	}
+	if t.font == "" {
+		t.font = defaultFont
+	}
+	if _, err := os.Stat(t.font); err != nil {
+		return nil, fmt.Errorf("couldn't find font: %v", err)
+	}
+
+	for role, c := range themes[0].colors {
+		t.colors[role] = c
+	}
+	for role, hex := range td.Colors {
+		if _, ok := t.colors[role]; !ok {
+			return nil, fmt.Errorf("unknown color role %s", role)
+		}
+
+		c, err := parseColor(hex)
+		if err != nil {
+			return nil, fmt.Errorf("color %s: %v", role, err)
+		}
+		t.colors[role] = c
+	}
+
+	return t, nil
+}
+
+// parseColor parses a #RRGGBB color
+func parseColor(hex string) (sdl.Color, error) {
+	if len(hex) != 7 || hex[0] != '#' {
+		return sdl.Color{}, fmt.Errorf("%q isn't a #RRGGBB color", hex)
+	}
+
+	v, err := strconv.ParseUint(hex[1:], 16, 32)
+	if err != nil {
+		return sdl.Color{}, fmt.Errorf("%q isn't a #RRGGBB color", hex)
+	}
+
+	return sdl.Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
+
+// useTheme draws the game with a theme, fonts change with the next scene
+func useTheme(name string) error {
+	t, err := loadTheme(name)
+	if err != nil {
+		return err
+	}
+	activeTheme = t
+
+	return nil
+}
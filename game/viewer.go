@@ -0,0 +1,209 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/MichaelThessel/spacee/app"
+	"github.com/veandco/go-sdl2/sdl"
+	mix "github.com/veandco/go-sdl2/sdl_mixer"
+	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+)
+
+// keyframeTicks is the distance between replay keyframes
+const keyframeTicks = 150
+
+// viewerSpeeds holds the replay speeds
+var viewerSpeeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// viewer plays back a replay
+// Seeking restores the closest keyframe before the target and resimulates
+// from there. Keyframes are captured the first time a tick is simulated.
+type viewer struct {
+	g         *Game
+	r         *sdl.Renderer
+	font      *ttf.Font
+	rp        *replay
+	ctrl      *replayer
+	keyframes []*snapshot
+	paused    bool
+	speed     int     // Index into viewerSpeeds
+	acc       float64 // Ticks owed to slow speeds
+	over      bool    // The game has ended
+}
+
+// newViewer creates a viewer for a replay of a game that has just been set up
+func newViewer(g *Game, rp *replay, ctrl *replayer) (*viewer, error) {
+	v := &viewer{
+		g:         g,
+		r:         g.a.GetRenderer(),
+		rp:        rp,
+		ctrl:      ctrl,
+		keyframes: []*snapshot{g.snapshot()},
+		speed:     2,
+	}
+
+	var err error
+	v.font, err = openFont(20)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Close closes the font
+func (v *viewer) Close() {
+	closeFont(v.font)
+}
+
+// update advances the replay according to the speed
+func (v *viewer) update() {
+	if v.paused || v.over {
+		return
+	}
+
+	v.acc += viewerSpeeds[v.speed]
+	for v.acc >= 1 && !v.over {
+		v.acc--
+		v.advance()
+	}
+}
+
+// advance simulates the next tick
+func (v *viewer) advance() {
+	if v.g.ticks >= len(v.rp.Inputs) {
+		v.over = true
+		return
+	}
+
+	v.g.tick()
+
+	if v.g.ticks%keyframeTicks == 0 && v.g.ticks/keyframeTicks == len(v.keyframes) {
+		v.keyframes = append(v.keyframes, v.g.snapshot())
+	}
+}
+
+// seek moves the replay to a tick
+func (v *viewer) seek(tick int) {
+	tick = max(0, min(tick, len(v.rp.Inputs)))
+
+	// Jump to the closest keyframe unless playing on is shorter
+	k := min(tick/keyframeTicks, len(v.keyframes)-1)
+	if tick < v.g.ticks || k*keyframeTicks > v.g.ticks {
+		v.g.restore(v.keyframes[k])
+		v.ctrl.tick = v.g.ticks
+		v.over = false
+	}
+
+	// Resimulate silently
+	volume := mix.Volume(-1, -1)
+	mix.Volume(-1, 0)
+	for v.g.ticks < tick && !v.over {
+		v.advance()
+	}
+	mix.Volume(-1, volume)
+
+	v.acc = 0
+}
+
+// step pauses the replay and moves it by n ticks
+func (v *viewer) step(n int) {
+	v.paused = true
+	v.seek(v.g.ticks + n)
+}
+
+// togglePause pauses or resumes the replay
+func (v *viewer) togglePause() {
+	v.paused = !v.paused
+}
+
+// changeSpeed changes the replay speed by step speeds
+func (v *viewer) changeSpeed(step int) {
+	v.speed = max(0, min(v.speed+step, len(viewerSpeeds)-1))
+}
+
+// Draw draws the replay HUD
+func (v *viewer) Draw() {
+	maxX, maxY := screenSize(v.r)
+	x := int32(maxX) / 2
+	c := themeColor(roleHUDText)
+
+	state := fmt.Sprintf("x%g", viewerSpeeds[v.speed])
+	if v.over {
+		state = "END"
+	} else if v.paused {
+		state = "PAUSED"
+	}
+	drawText(v.r, v.font, fmt.Sprintf("REPLAY  TICK %d / %d  %s", v.g.ticks, len(v.rp.Inputs), state), x, 90, c)
+
+	if v.paused || v.over {
+		drawText(v.r, v.font, "SPACE PLAY  LEFT/RIGHT STEP  UP/DOWN SPEED  PGUP/PGDN SEEK  ESC MENU", x, int32(maxY)-100, c)
+	}
+}
+
+// watchReplay loads a replay file and opens it in the viewer
+func (g *Game) watchReplay(path string) error {
+	rp, err := loadReplay(path)
+	if err != nil {
+		return err
+	}
+
+	g.mode, err = findMode(rp.Mode)
+	if err != nil {
+		return err
+	}
+	g.rp = rp
+
+	return g.switchScene(sceneReplay)
+}
+
+// sceneReplay sets up the replay viewer for g.rp
+func (g *Game) sceneReplay() error {
+	g.c = g.rp.Config.config()
+	g.seed = g.rp.Seed
+
+	// Replays bring the scripts they were recorded with
+	g.hooks = nil
+	if len(g.rp.Scripts) > 0 {
+		var err error
+		g.hooks, err = newScripts(g.rp.Scripts, g.rng)
+		if err != nil {
+			return err
+		}
+		g.a.Own(app.SceneScope, g.hooks.Close)
+	}
+
+	if err := g.startGame(); err != nil {
+		return err
+	}
+
+	ctrl := &replayer{inputs: g.rp.Inputs}
+	g.ctrl = ctrl
+
+	var err error
+	g.viewer, err = newViewer(g, g.rp, ctrl)
+	if err != nil {
+		return err
+	}
+	g.a.Own(app.SceneScope, g.viewer.Close)
+
+	// Advance the replay
+	g.a.RegisterUpdateCallback(g.viewer.update)
+
+	// Draw replay HUD
+	g.a.RegisterRenderCallback(2, g.viewer.Draw)
+
+	second := int(g.a.GetFrameRate())
+	g.a.RegisterKeyCallback(sdl.K_SPACE, g.viewer.togglePause)                             // pause
+	g.a.RegisterKeyCallback(sdl.K_UP, func() { g.viewer.changeSpeed(1) })                  // faster
+	g.a.RegisterKeyCallback(sdl.K_DOWN, func() { g.viewer.changeSpeed(-1) })               // slower
+	g.a.RegisterKeyCallback(sdl.K_RIGHT, func() { g.viewer.step(1) })                      // next tick
+	g.a.RegisterKeyCallback(sdl.K_LEFT, func() { g.viewer.step(-1) })                      // previous tick
+	g.a.RegisterKeyCallback(sdl.K_PAGEDOWN, func() { g.viewer.seek(g.ticks + 10*second) }) // forward
+	g.a.RegisterKeyCallback(sdl.K_PAGEUP, func() { g.viewer.seek(g.ticks - 10*second) })   // back
+	g.a.RegisterKeyCallback(sdl.K_HOME, func() { g.viewer.seek(0) })                       // start
+	g.a.RegisterKeyCallback(sdl.K_END, func() { g.viewer.seek(len(g.rp.Inputs)) })         // end
+	g.a.RegisterKeyCallback(sdl.K_ESCAPE, func() { g.changeScene(sceneStart) })            // menu
+
+	return nil
+}
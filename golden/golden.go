@@ -0,0 +1,191 @@
+// Package golden renders scenes headless and compares the frames with
+// checked-in golden images
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/MichaelThessel/spacee/app"
+	"github.com/MichaelThessel/spacee/game"
+)
+
+// Case describes a scene frame to compare
+type Case struct {
+	Name    string        // Name of the golden image
+	Options *game.Options // Options to start the game with
+	Frame   int           // Frame to capture
+}
+
+// Cases holds the scene frames that are covered by golden images
+var Cases = []Case{
+	{Name: "start", Options: &game.Options{Seed: 1, Scene: "start"}, Frame: 5},
+	{Name: "play", Options: &game.Options{Seed: 1, Scene: "play"}, Frame: 60},
+	{Name: "end", Options: &game.Options{Seed: 1, Scene: "end", Score: 1230}, Frame: 5},
+}
+
+// Tolerance defines how much a frame may deviate from its golden image
+type Tolerance struct {
+	Channel uint8   // Max difference per color channel before a pixel counts as changed
+	Pixels  float64 // Max fraction of changed pixels
+}
+
+// DefaultTolerance allows for small differences between software renderers
+var DefaultTolerance = Tolerance{Channel: 8, Pixels: 0.001}
+
+// Run captures all cases and compares them with the golden images in dir
+// With update set the golden images are rewritten instead
+func Run(c *app.Config, dir string, update bool, t Tolerance) error {
+	var failed []string
+
+	for _, tc := range Cases {
+		changed, err := Check(c, dir, tc, update, t)
+		switch {
+		case update && err == nil:
+			fmt.Printf("updated %s\n", filepath.Join(dir, tc.Name+".png"))
+		case err == nil:
+			fmt.Printf("ok     %s (%.4f%% changed)\n", tc.Name, changed*100)
+		case changed > 0:
+			fmt.Printf("FAIL   %s (%.4f%% changed)\n", tc.Name, changed*100)
+			failed = append(failed, tc.Name)
+		default:
+			return fmt.Errorf("%s: %v", tc.Name, err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("frames differ from golden images: %v", failed)
+	}
+
+	return nil
+}
+
+// Check captures a case and compares it with its golden image in dir and
+// returns the fraction of changed pixels, frames over the tolerance are an
+// error
+// With update set the golden image is rewritten instead.
+func Check(c *app.Config, dir string, tc Case, update bool, t Tolerance) (float64, error) {
+	frame, err := capture(c, tc)
+	if err != nil {
+		return 0, err
+	}
+
+	path := filepath.Join(dir, tc.Name+".png")
+	if update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, err
+		}
+		return 0, save(path, frame)
+	}
+
+	want, err := load(path)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("no golden image %s, create it with -update-golden or go test ./golden -update", path)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	diff, changed := compare(want, frame, t.Channel)
+	if changed <= t.Pixels {
+		return changed, nil
+	}
+
+	// Keep the evidence next to the golden image for review
+	save(filepath.Join(dir, tc.Name+".actual.png"), frame)
+	save(filepath.Join(dir, tc.Name+".diff.png"), diff)
+
+	return changed, fmt.Errorf("%.4f%% of the pixels changed, see %s", changed*100, tc.Name+".diff.png")
+}
+
+// capture runs a case in a fresh headless app and captures its frame
+func capture(c *app.Config, tc Case) (*image.RGBA, error) {
+	hc := *c
+	hc.Headless = true
+	hc.ScreenshotAt = 0
+
+	a, err := app.New(&hc)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Destroy()
+
+	// Keep the local high scores out of the frames
+	dataDir, err := os.MkdirTemp("", "golden")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dataDir)
+
+	o := *tc.Options
+	o.DataDir = dataDir
+	if _, err := game.New(a, &o); err != nil {
+		return nil, err
+	}
+
+	a.RunFrames(tc.Frame)
+
+	return a.Capture()
+}
+
+// compare compares two images and returns a diff image and the fraction of
+// pixels that differ by more than the channel tolerance
+func compare(want, got image.Image, channel uint8) (*image.RGBA, float64) {
+	b := want.Bounds()
+	diff := image.NewRGBA(b)
+
+	if b != got.Bounds() {
+		return diff, 1
+	}
+
+	changed := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			wr, wg, wb, _ := want.At(x, y).RGBA()
+			gr, gg, gb, _ := got.At(x, y).RGBA()
+
+			i := diff.PixOffset(x, y)
+			diff.Pix[i+3] = 0xFF
+			if delta(wr, gr) > channel || delta(wg, gg) > channel || delta(wb, gb) > channel {
+				diff.Pix[i] = 0xFF
+				changed++
+			}
+		}
+	}
+
+	return diff, float64(changed) / float64(b.Dx()*b.Dy())
+}
+
+// delta returns the 8 bit difference of two 16 bit color channels
+func delta(a, b uint32) uint8 {
+	if a > b {
+		return uint8((a - b) >> 8)
+	}
+
+	return uint8((b - a) >> 8)
+}
+
+// load loads a PNG
+func load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// save saves an image as PNG
+func save(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
@@ -0,0 +1,56 @@
+package golden
+
+import (
+	"flag"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/MichaelThessel/spacee/app"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images instead of comparing")
+
+func TestMain(m *testing.M) {
+	// SDL has to stay on the thread it was initialized on
+	runtime.LockOSThread()
+
+	// The game loads its assets relative to the repository root
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestScenes(t *testing.T) {
+	// Images are only created with -update, missing images of single cases
+	// still fail below
+	if _, err := os.Stat("golden/images"); os.IsNotExist(err) && !*update {
+		t.Skip("no golden images, create them with go test ./golden -update and commit golden/images")
+	}
+
+	quit, err := app.Init(true)
+	if err != nil {
+		t.Skipf("sdl isn't available: %v", err)
+	}
+	defer quit()
+
+	// The dummy driver may lack a software renderer
+	c := &app.Config{Width: 1200, Height: 800, Title: "e-Space", FrameRate: 30, Headless: true}
+	a, err := app.New(c)
+	if err != nil {
+		t.Skipf("headless rendering isn't available: %v", err)
+	}
+	a.Destroy()
+
+	for _, tc := range Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			changed, err := Check(c, "golden/images", tc, *update, DefaultTolerance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("%.4f%% changed", changed*100)
+		})
+	}
+}
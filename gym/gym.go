@@ -0,0 +1,131 @@
+// Package gym exposes a game environment over a line based JSON protocol so
+// agents written in other languages can drive it
+//
+// Each request is a JSON object on a single line, answered by a single line:
+//
+//	{"cmd": "spec"}                 -> {"actions": 6, "observation_type": "vector"}
+//	{"cmd": "reset", "seed": 1}     -> {"observation": {...}, "info": {...}}
+//	{"cmd": "step", "action": 3}    -> {"observation": {...}, "reward": 30, "done": false, "info": {...}}
+//	{"cmd": "close"}                -> {}
+//
+// A missing seed resets to seed 0, which is as reproducible as any other.
+// Vector observations carry "vector", a list of numbers. Frame observations
+// carry "frame", "width" and "height"; the grayscale pixels of "frame" are
+// encoded as a base64 string like all JSON byte slices.
+//
+// Failed requests are answered with {"error": "..."}.
+package gym
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/MichaelThessel/spacee/game"
+)
+
+// request is a protocol request
+type request struct {
+	Cmd    string `json:"cmd"`
+	Seed   int64  `json:"seed"`
+	Action int    `json:"action"`
+}
+
+// response is a protocol response
+type response struct {
+	Error       string            `json:"error,omitempty"`
+	Actions     int               `json:"actions,omitempty"`
+	ObsType     string            `json:"observation_type,omitempty"`
+	Observation *game.Observation `json:"observation,omitempty"`
+	Reward      *float64          `json:"reward,omitempty"`
+	Done        *bool             `json:"done,omitempty"`
+	Info        *game.Info        `json:"info,omitempty"`
+}
+
+// Serve answers requests read from r on w until r is exhausted or a close
+// request is received
+func Serve(env *game.Env, obsType string, r io.Reader, w io.Writer) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	enc := json.NewEncoder(w)
+
+	for s.Scan() {
+		var req request
+		var res *response
+		if err := json.Unmarshal(s.Bytes(), &req); err != nil {
+			res = &response{Error: fmt.Sprintf("invalid request: %v", err)}
+		} else {
+			res = handle(env, obsType, &req)
+		}
+
+		if err := enc.Encode(res); err != nil {
+			return err
+		}
+
+		if req.Cmd == "close" {
+			return nil
+		}
+	}
+
+	return s.Err()
+}
+
+// ListenAndServe serves connections on a loopback TCP address one at a time
+func ListenAndServe(env *game.Env, obsType string, addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid gym address %s: %v", addr, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("gym address %s isn't on localhost", addr)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen on %s: %v", addr, err)
+	}
+	defer l.Close()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		err = Serve(env, obsType, c, c)
+		c.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers a single request
+func handle(env *game.Env, obsType string, req *request) *response {
+	switch req.Cmd {
+	case "spec":
+		return &response{Actions: env.ActionCount(), ObsType: obsType}
+	case "reset":
+		obs, info, err := env.Reset(req.Seed)
+		if err != nil {
+			return &response{Error: err.Error()}
+		}
+		return &response{Observation: obs, Info: info}
+	case "step":
+		act, err := env.Action(req.Action)
+		if err != nil {
+			return &response{Error: err.Error()}
+		}
+		obs, reward, done, info, err := env.Step(act)
+		if err != nil {
+			return &response{Error: err.Error()}
+		}
+		return &response{Observation: obs, Reward: &reward, Done: &done, Info: info}
+	case "close":
+		return &response{}
+	default:
+		return &response{Error: fmt.Sprintf("unknown command %s", req.Cmd)}
+	}
+}
@@ -0,0 +1,71 @@
+package gym
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/MichaelThessel/spacee/game"
+)
+
+func TestServe(t *testing.T) {
+	// Requests that don't play a game need neither SDL nor an app
+	env, err := game.NewEnv(nil, &game.EnvConfig{Observation: game.ObserveVector})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqR, reqW := io.Pipe()
+	resR, resW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(env, game.ObserveVector, reqR, resW)
+		resW.Close()
+	}()
+
+	tests := []struct {
+		req  string
+		want string
+	}{
+		{`{"cmd": "spec"}`, `{"actions":6,"observation_type":"vector"}`},
+		{`{"cmd": "step", "action": 0}`, `{"error":"game is over, reset the environment"}`},
+		{`{"cmd": "step", "action": 9}`, `{"error":"invalid action 9"}`},
+		{`{"cmd": "jump"}`, `{"error":"unknown command jump"}`},
+		{`not json`, `{"error":"invalid request: invalid character 'o' in literal null (expecting 'u')"}`},
+		{`{"cmd": "close"}`, `{}`},
+	}
+
+	res := bufio.NewScanner(resR)
+	for _, tt := range tests {
+		if _, err := io.WriteString(reqW, tt.req+"\n"); err != nil {
+			t.Fatalf("%s: couldn't write request: %v", tt.req, err)
+		}
+		if !res.Scan() {
+			t.Fatalf("%s: no response: %v", tt.req, res.Err())
+		}
+		if got := strings.TrimSpace(res.Text()); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.req, got, tt.want)
+		}
+		if !json.Valid(res.Bytes()) {
+			t.Errorf("%s: response isn't a single JSON line: %s", tt.req, res.Text())
+		}
+	}
+
+	// Close ends the session
+	if err := <-done; err != nil {
+		t.Errorf("Serve returned %v after close", err)
+	}
+	if res.Scan() {
+		t.Errorf("unexpected response after close: %s", res.Text())
+	}
+}
+
+func TestListenAndServeLoopback(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:5555", ":5555", "example.com:5555", "5555"} {
+		if err := ListenAndServe(nil, game.ObserveVector, addr); err == nil {
+			t.Errorf("ListenAndServe(%q) accepted a non loopback address", addr)
+		}
+	}
+}
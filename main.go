@@ -1,59 +1,255 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/MichaelThessel/spacee/app"
 	"github.com/MichaelThessel/spacee/game"
-	"github.com/veandco/go-sdl2/sdl"
-	mix "github.com/veandco/go-sdl2/sdl_mixer"
-	ttf "github.com/veandco/go-sdl2/sdl_ttf"
+	"github.com/MichaelThessel/spacee/golden"
+	"github.com/MichaelThessel/spacee/gym"
 )
 
 func main() {
-	// TODO: config needs to come from flags
+	os.Exit(run())
+}
+
+// run runs the game or tool the flags select and returns the exit code
+func run() int {
+	headless := flag.Bool("headless", false, "render offscreen using the dummy video driver")
+	screenshotAt := flag.Int("screenshot-at", 0, "save frame `N` as a PNG")
+	screenshotPath := flag.String("screenshot", "screenshot.png", "screenshot `file`")
+	goldenDir := flag.String("golden", "", "compare scene frames with the golden images in `dir` and exit")
+	updateGolden := flag.Bool("update-golden", false, "rewrite the golden images instead of comparing")
+	goldenChannel := flag.Uint("golden-channel", uint(golden.DefaultTolerance.Channel), "max color channel difference of a golden image pixel")
+	goldenPixels := flag.Float64("golden-pixels", golden.DefaultTolerance.Pixels, "max fraction of changed golden image pixels")
+	seed := flag.Int64("seed", 0, "random `seed` (0: random)")
+	mode := flag.String("mode", "classic", "game `mode` (classic, endless, timeattack or daily)")
+	bot := flag.String("bot", "", fmt.Sprintf("let a bot play, one of %v", game.BotNames()))
+	batch := flag.Int("batch", 0, "play `N` games with the bot without rendering and print the scores as CSV")
+	batchOut := flag.String("batch-out", "", "write the batch CSV to `file` instead of stdout")
+	batchMaxTicks := flag.Int("batch-max-ticks", 54000, "cut batch games off after `N` ticks and record a timeout (0: no limit)")
+	gymAddr := flag.String("gym", "", "serve a training environment on stdio or a local TCP `address`")
+	gymObs := flag.String("gym-obs", game.ObserveVector, "gym observation `type` (vector or frame)")
+	gymFrameSkip := flag.Int("gym-frame-skip", 4, "ticks each gym action is repeated for")
+	gymFrameWidth := flag.Int("gym-frame-width", 120, "width of gym frame observations")
+	gymFrameHeight := flag.Int("gym-frame-height", 80, "height of gym frame observations")
+	gymDeathPenalty := flag.Float64("gym-death-penalty", 100, "gym reward subtracted for each life lost")
+	levels := flag.String("levels", "assets/levels", "`dir` of the level files")
+	edit := flag.String("edit", "", "open level `file` in the level editor")
+	replay := flag.String("replay", "", "watch a replay `file`")
+	mouseAim := flag.Bool("mouse-aim", false, "move the tank to the mouse cursor and fire with a click")
+	theme := flag.String("theme", "", "draw with a built-in theme (default, colorblind, high-contrast) or a JSON theme `file`")
+	scripts := flag.String("scripts", "", "load the Lua mod scripts of `dir`")
+	configPath := flag.String("config", "", "load the game config from a JSON `file`")
+	dev := flag.Bool("dev", false, "reload the config file and assets when they change, serve expvar & pprof on -debug-addr")
+	debugAddr := flag.String("debug-addr", "localhost:6060", "local `address` of the expvar & pprof endpoint in dev mode")
+	metricsLog := flag.String("metrics-log", "", "append frame time reports as JSON lines to `file` (-: stdout)")
+	metricsInterval := flag.Duration("metrics-interval", 5*time.Second, "time between frame time reports")
+	benchFrames := flag.Int("bench-frames", 0, "render `N` frames of a bot session, print the frame times and exit")
+	benchBudget := flag.Float64("bench-budget", 0, "fail the benchmark if the p95 frame time exceeds `ms` (0: off)")
+	verifyDaily := flag.String("verify-daily", "", "check a shared daily challenge `result` and exit")
+	verifyReplay := flag.String("verify-replay", "", "replay a daily challenge replay `file` without rendering, check its score and exit")
+	logLevel := flag.String("log-level", "info", "log `level` (debug, info, warn or error)")
+	flag.Parse()
+
+	// Logs go to stderr, stdout carries results and the gym protocol
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log level %s\n", *logLevel)
+		return 2
+	}
+	history := app.NewLogHistory(200)
+	slog.SetDefault(slog.New(slog.NewTextHandler(
+		io.MultiWriter(os.Stderr, history),
+		&slog.HandlerOptions{Level: level},
+	)))
+
+	if *verifyDaily != "" {
+		score, seed, err := game.VerifyDaily(*verifyDaily)
+		if err != nil {
+			slog.Error("invalid daily challenge result", "err", err)
+			return 1
+		}
+		fmt.Printf("well-formed result: score %d, seed %d (only the replay proves the score)\n", score, seed)
+		return 0
+	}
+
+	// TODO: remaining config needs to come from flags
 	config := &app.Config{
-		Width:     1200,
-		Height:    800,
-		Title:     "e-Space",
-		FrameRate: 30,
+		Width:          1200,
+		Height:         800,
+		Title:          "e-Space",
+		FrameRate:      30,
+		Headless:       *headless,
+		ScreenshotAt:   *screenshotAt,
+		ScreenshotPath: *screenshotPath,
+
+		MetricsInterval: *metricsInterval,
+		LogHistory:      history,
 	}
 
-	if err := sdl.Init(sdl.INIT_EVERYTHING); err != nil {
-		fmt.Printf("could not initialize sdl: %v", err)
-		os.Exit(1)
+	if *metricsLog == "-" {
+		config.MetricsLog = os.Stdout
+	} else if *metricsLog != "" {
+		f, err := os.OpenFile(*metricsLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			slog.Error("couldn't open metrics log", "err", err)
+			return 1
+		}
+		defer f.Close()
+		config.MetricsLog = f
 	}
-	defer sdl.Quit()
 
-	if err := ttf.Init(); err != nil {
-		fmt.Printf("could not initialize ttf: %v", err)
-		os.Exit(1)
+	// Benchmarks render offscreen as fast as possible
+	if *benchFrames > 0 {
+		config.Headless = true
 	}
-	defer ttf.Quit()
 
-	if err := mix.Init(0); err != nil {
-		fmt.Printf("could not initialize mixer: %v", err)
-		os.Exit(1)
+	// Batch runs & replay checks don't need any output
+	if *batch > 0 || *verifyReplay != "" {
+		config.Headless = true
+		config.NoRender = true
 	}
-	defer mix.Quit()
 
-	sndfmt := uint16(mix.DEFAULT_FORMAT)
-	if err := mix.OpenAudio(44100, sndfmt, 2, 1024); err != nil {
-		fmt.Printf("could not initialize audio: %v", err)
-		os.Exit(1)
+	// Agents see either the state or the frames
+	if *gymAddr != "" {
+		config.Headless = true
+		config.NoRender = *gymObs != game.ObserveFrame
+	}
+
+	// Golden images always render on the dummy drivers
+	quit, err := app.Init(config.Headless || *goldenDir != "")
+	if err != nil {
+		slog.Error("couldn't initialize", "err", err)
+		return 1
+	}
+	defer quit()
+
+	if *goldenDir != "" {
+		err := golden.Run(config, *goldenDir, *updateGolden, golden.Tolerance{
+			Channel: uint8(*goldenChannel),
+			Pixels:  *goldenPixels,
+		})
+		if err != nil {
+			slog.Error("golden images failed", "err", err)
+			return 1
+		}
+		return 0
+	}
+
+	options := &game.Options{
+		Seed:   *seed,
+		Bot:    *bot,
+		Mode:   *mode,
+		Replay: *replay,
+		Levels: *levels,
+		Level:  *edit,
+
+		MouseAim: *mouseAim,
+		Theme:    *theme,
+
+		Scripts: *scripts,
+		Config:  *configPath,
+		Dev:     *dev,
+	}
+	if *edit != "" {
+		options.Scene = "editor"
+	}
+	// VSync is picked when the renderer is created
+	if !config.Headless {
+		game.ConfigureApp(options, config)
 	}
 
 	a, err := app.New(config)
 	if err != nil {
-		fmt.Printf("couldn't set up window %v", err)
+		slog.Error("couldn't set up window", "err", err)
+		return 1
 	}
 	defer a.Destroy()
 
-	if _, err := game.New(a); err != nil {
-		fmt.Printf("couldn't create game %v", err)
-		os.Exit(1)
+	if *benchFrames > 0 {
+		report, err := game.Benchmark(a, options, *benchFrames)
+		if err != nil {
+			slog.Error("benchmark failed", "err", err)
+			return 1
+		}
+		report.Write(os.Stdout)
+
+		if *benchBudget > 0 && report.Frame.P95 > *benchBudget {
+			fmt.Printf("p95 frame time %.2fms exceeds the budget of %.2fms\n", report.Frame.P95, *benchBudget)
+			return 1
+		}
+		return 0
+	}
+
+	if *batch > 0 {
+		out := os.Stdout
+		if *batchOut != "" {
+			out, err = os.Create(*batchOut)
+			if err != nil {
+				slog.Error("couldn't create batch output", "err", err)
+				return 1
+			}
+			defer out.Close()
+		}
+
+		if err := game.Batch(a, options, *batch, *batchMaxTicks, out); err != nil {
+			slog.Error("batch failed", "err", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *verifyReplay != "" {
+		score, err := game.VerifyReplay(a, *verifyReplay)
+		if err != nil {
+			slog.Error("invalid replay", "err", err)
+			return 1
+		}
+		fmt.Printf("valid replay: score %d\n", score)
+		return 0
+	}
+
+	if *gymAddr != "" {
+		env, err := game.NewEnv(a, &game.EnvConfig{
+			Observation:  *gymObs,
+			FrameSkip:    *gymFrameSkip,
+			FrameWidth:   *gymFrameWidth,
+			FrameHeight:  *gymFrameHeight,
+			DeathPenalty: *gymDeathPenalty,
+		})
+		if err != nil {
+			slog.Error("couldn't create gym environment", "err", err)
+			return 1
+		}
+
+		if *gymAddr == "stdio" {
+			err = gym.Serve(env, *gymObs, os.Stdin, os.Stdout)
+		} else {
+			err = gym.ListenAndServe(env, *gymObs, *gymAddr)
+		}
+		if err != nil {
+			slog.Error("gym failed", "err", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *dev {
+		if err := a.ServeDebug(*debugAddr); err != nil {
+			slog.Warn("couldn't serve debug endpoint", "err", err)
+		}
+	}
+
+	if _, err := game.New(a, options); err != nil {
+		slog.Error("couldn't create game", "err", err)
+		return 1
 	}
 
-	os.Exit(a.Run())
+	return a.Run()
 }